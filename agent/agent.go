@@ -0,0 +1,46 @@
+// Package agent is the protocol between updawg agent, which checks targets
+// from a remote location, and the updawg serve instance it reports to. Agents
+// register with their location, fetch the targets assigned to it and post
+// back their results, all authenticated with a shared token.
+package agent
+
+import (
+	"time"
+
+	"github.com/tehlordvortex/updawg/models"
+)
+
+// Registration is sent by agents when they start.
+type Registration struct {
+	Location string `json:"location"`
+	Hostname string `json:"hostname"`
+}
+
+// Registered is the server's response to a registration.
+type Registered struct {
+	Id string `json:"id"`
+}
+
+// Report is a check result sent by an agent.
+type Report struct {
+	TargetId   string                 `json:"target_id"`
+	Status     models.Status          `json:"status"`
+	Message    string                 `json:"message"`
+	DurationMs float64                `json:"duration_ms"`
+	Data       map[string]interface{} `json:"data"`
+}
+
+func NewReport(result models.CheckResult) Report {
+	return Report{
+		TargetId:   result.TargetId,
+		Status:     result.Status,
+		Message:    result.Message,
+		DurationMs: float64(result.Duration.Microseconds()) / 1000,
+		Data:       result.Data,
+	}
+}
+
+// Duration returns the duration of the reported check.
+func (r Report) Duration() time.Duration {
+	return time.Duration(r.DurationMs * float64(time.Millisecond))
+}
@@ -0,0 +1,139 @@
+package agent
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tehlordvortex/updawg/models"
+)
+
+var errUnknownAgent = errors.New("agent is not registered")
+
+// Client talks to the updawg serve instance an agent reports to. It
+// registers again if the server no longer knows the agent, such as after its
+// database was reset.
+type Client struct {
+	baseUrl  string
+	token    string
+	location string
+	http     *http.Client
+
+	mut sync.Mutex
+	id  string
+}
+
+func NewClient(baseUrl, token, location string) *Client {
+	return &Client{
+		baseUrl:  strings.TrimSuffix(baseUrl, "/"),
+		token:    token,
+		location: location,
+		http:     &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Register registers the agent with the server, returning its id.
+func (c *Client) Register(ctx context.Context) (string, error) {
+	hostname, _ := os.Hostname()
+
+	var registered Registered
+	if err := c.do(ctx, http.MethodPost, "/agents", Registration{Location: c.location, Hostname: hostname}, &registered); err != nil {
+		return "", fmt.Errorf("failed to register: %v", err)
+	}
+
+	c.mut.Lock()
+	c.id = registered.Id
+	c.mut.Unlock()
+
+	return registered.Id, nil
+}
+
+// Targets returns the targets assigned to the agent's location.
+func (c *Client) Targets(ctx context.Context) ([]models.Target, error) {
+	var targets []models.Target
+	err := c.doAsAgent(ctx, http.MethodGet, "/targets", nil, &targets)
+
+	return targets, err
+}
+
+// Report sends check results to the server.
+func (c *Client) Report(ctx context.Context, reports []Report) error {
+	return c.doAsAgent(ctx, http.MethodPost, "/results", reports, nil)
+}
+
+// doAsAgent makes a request to one of the agent's endpoints, registering
+// first if needed.
+func (c *Client) doAsAgent(ctx context.Context, method, path string, body, out interface{}) error {
+	c.mut.Lock()
+	id := c.id
+	c.mut.Unlock()
+
+	if id == "" {
+		var err error
+		if id, err = c.Register(ctx); err != nil {
+			return err
+		}
+	}
+
+	err := c.do(ctx, method, "/agents/"+id+path, body, out)
+	if !errors.Is(err, errUnknownAgent) {
+		return err
+	}
+
+	if id, err = c.Register(ctx); err != nil {
+		return err
+	}
+
+	return c.do(ctx, method, "/agents/"+id+path, body, out)
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var reqBody io.Reader
+	if body != nil {
+		bodyJson, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+
+		reqBody = bytes.NewReader(bodyJson)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseUrl+path, reqBody)
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	res, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == http.StatusNotFound && strings.HasPrefix(path, "/agents/") {
+		return errUnknownAgent
+	}
+
+	if res.StatusCode < 200 || res.StatusCode > 299 {
+		message, _ := io.ReadAll(io.LimitReader(res.Body, 1024))
+		return fmt.Errorf("%s %s: %s: %s", method, path, res.Status, strings.TrimSpace(string(message)))
+	}
+
+	if out == nil {
+		return nil
+	}
+
+	return json.NewDecoder(res.Body).Decode(out)
+}
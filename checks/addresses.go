@@ -0,0 +1,164 @@
+package checks
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tehlordvortex/updawg/models"
+)
+
+// Kinds which connect to a single host and can be checked once per resolved
+// address. Content targets are excluded as they keep a single snapshot
+// history.
+var perAddressKinds = []string{
+	models.TargetKindHttp,
+	models.TargetKindSmtp,
+	models.TargetKindImap,
+	models.TargetKindPop3,
+	models.TargetKindUdp,
+	models.TargetKindIcmp,
+	models.TargetKindSsh,
+	models.TargetKindTls,
+}
+
+type addressesConfig struct {
+	// AllAddresses checks every A and AAAA record of the target's host
+	// separately instead of whichever address the resolver returns first.
+	AllAddresses bool `json:"all_addresses"`
+}
+
+type pinnedAddressKey struct{}
+
+// pinnedAddress directs connections made for host to ip instead of resolving
+// it. The host is still used for the Host header, SNI and certificate
+// verification.
+type pinnedAddress struct {
+	host string
+	ip   net.IP
+}
+
+func withPinnedAddress(ctx context.Context, host string, ip net.IP) context.Context {
+	return context.WithValue(ctx, pinnedAddressKey{}, pinnedAddress{host: host, ip: ip})
+}
+
+// pinnedIp returns the address host has been pinned to in ctx, if any.
+func pinnedIp(ctx context.Context, host string) (net.IP, bool) {
+	pinned, ok := ctx.Value(pinnedAddressKey{}).(pinnedAddress)
+	if !ok || !strings.EqualFold(pinned.host, host) {
+		return nil, false
+	}
+
+	return pinned.ip, true
+}
+
+// dialContext dials addr, connecting to the pinned address instead if addr's
+// host has been pinned in ctx.
+func dialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	if host, port, err := net.SplitHostPort(addr); err == nil {
+		if ip, ok := pinnedIp(ctx, host); ok {
+			addr = net.JoinHostPort(ip.String(), port)
+		}
+	}
+
+	var dialer net.Dialer
+	return dialer.DialContext(ctx, network, addr)
+}
+
+// checkAllAddresses resolves the target's host and runs checker against each
+// address concurrently. The target is up when every address is up, down when
+// every address is down and partial when only some are down.
+func checkAllAddresses(ctx context.Context, qe models.QueryExecutor, target *models.Target, checker Checker) models.CheckResult {
+	_, host, _, err := parseAddress(target.Uri, "")
+	if err != nil {
+		return unknown("invalid uri: %v", err)
+	}
+
+	if net.ParseIP(host) != nil {
+		return checker(ctx, qe, target)
+	}
+
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return down("%v", err)
+	}
+
+	sort.Slice(addrs, func(i, j int) bool {
+		return addrs[i].IP.String() < addrs[j].IP.String()
+	})
+
+	results := make([]models.CheckResult, len(addrs))
+	var wg sync.WaitGroup
+	for i, addr := range addrs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			start := time.Now()
+			results[i] = checker(withPinnedAddress(ctx, host, addr.IP), qe, target)
+			if results[i].Duration == 0 {
+				results[i].Duration = time.Since(start)
+			}
+		}()
+	}
+	wg.Wait()
+
+	addresses := make(map[string]interface{})
+	var failures []string
+	var downCount, degradedCount, unknownCount int
+	var duration time.Duration
+
+	for i, addr := range addrs {
+		r := results[i]
+		addresses[addr.IP.String()] = map[string]interface{}{
+			"status":      r.Status,
+			"message":     r.Message,
+			"duration_ms": float64(r.Duration.Microseconds()) / 1000,
+			"data":        r.Data,
+		}
+
+		duration = max(duration, r.Duration)
+
+		switch r.Status {
+		case models.StatusDown:
+			downCount++
+		case models.StatusDegraded:
+			degradedCount++
+		case models.StatusUnknown:
+			unknownCount++
+		}
+
+		if r.Status != models.StatusUp {
+			failures = append(failures, fmt.Sprintf("%s: %s", addr.IP, r.Message))
+		}
+	}
+
+	summary := fmt.Sprintf("%d/%d addresses up", len(addrs)-downCount-degradedCount-unknownCount, len(addrs))
+	if len(failures) > 0 {
+		summary += " (" + strings.Join(failures, "; ") + ")"
+	}
+
+	var result models.CheckResult
+	switch {
+	case downCount == len(addrs):
+		result = down("%s", summary)
+	case downCount > 0:
+		result = newResult(models.StatusPartial, "partial outage: %s", summary)
+	case degradedCount > 0:
+		result = degraded("%s", summary)
+	case unknownCount > 0:
+		result = unknown("%s", summary)
+	default:
+		result = up("%s", summary)
+	}
+
+	result.Data["host"] = host
+	result.Data["addresses"] = addresses
+	result.Duration = duration
+
+	return result
+}
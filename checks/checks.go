@@ -0,0 +1,135 @@
+package checks
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/tehlordvortex/updawg/config"
+	"github.com/tehlordvortex/updawg/models"
+)
+
+// Checker performs a single check against a target. The returned result does
+// not need to set TargetId, and Duration is filled in if left empty. Checkers
+// which keep state between checks store it through qe.
+type Checker func(ctx context.Context, qe models.QueryExecutor, target *models.Target) models.CheckResult
+
+var logger = log.New(config.GetLogFile(), "", log.Default().Flags()|log.Lmsgprefix|log.Lshortfile)
+
+var checkers = map[string]Checker{
+	models.TargetKindHttp:        checkHttp,
+	models.TargetKindExec:        checkExec,
+	models.TargetKindSql:         checkSql,
+	models.TargetKindSmtp:        checkMail(smtpProtocol),
+	models.TargetKindImap:        checkMail(imapProtocol),
+	models.TargetKindPop3:        checkMail(pop3Protocol),
+	models.TargetKindUdp:         checkUdp,
+	models.TargetKindIcmp:        checkIcmp,
+	models.TargetKindTransaction: checkTransaction,
+	models.TargetKindContent:     checkContent,
+	models.TargetKindDomain:      checkDomain,
+	models.TargetKindSsh:         checkSsh,
+	models.TargetKindComposite:   checkComposite,
+	models.TargetKindTls:         checkTls,
+}
+
+// Run checks the target using the checker registered for its kind, or for
+// targets checked from remote locations, from the results reported by
+// agents.
+func Run(ctx context.Context, qe models.QueryExecutor, target *models.Target) models.CheckResult {
+	if len(Locations(target)) > 0 {
+		return runChecker(ctx, qe, target, checkLocations)
+	}
+
+	return RunLocal(ctx, qe, target)
+}
+
+// RunLocal checks the target from this host, as agents do for the targets
+// assigned to their location.
+func RunLocal(ctx context.Context, qe models.QueryExecutor, target *models.Target) models.CheckResult {
+	checker, ok := checkers[target.Kind]
+	if !ok {
+		result := unknown("no checker for kind %s", target.Kind)
+		result.TargetId = target.Id()
+
+		return result
+	}
+
+	var cfg addressesConfig
+	_ = target.DecodeConfig(&cfg)
+
+	if cfg.AllAddresses && slices.Contains(perAddressKinds, target.Kind) {
+		return runChecker(ctx, qe, target, func(ctx context.Context, qe models.QueryExecutor, target *models.Target) models.CheckResult {
+			return checkAllAddresses(ctx, qe, target, checker)
+		})
+	}
+
+	return runChecker(ctx, qe, target, checker)
+}
+
+func runChecker(ctx context.Context, qe models.QueryExecutor, target *models.Target, checker Checker) models.CheckResult {
+	start := time.Now()
+	result := checker(ctx, qe, target)
+
+	result.TargetId = target.Id()
+	if result.Duration == 0 {
+		result.Duration = time.Since(start)
+	}
+
+	return result
+}
+
+func newResult(status models.Status, format string, args ...interface{}) models.CheckResult {
+	return models.CheckResult{
+		Status:  status,
+		Message: fmt.Sprintf(format, args...),
+		Data:    make(map[string]interface{}),
+	}
+}
+
+func up(format string, args ...interface{}) models.CheckResult {
+	return newResult(models.StatusUp, format, args...)
+}
+
+func degraded(format string, args ...interface{}) models.CheckResult {
+	return newResult(models.StatusDegraded, format, args...)
+}
+
+func down(format string, args ...interface{}) models.CheckResult {
+	return newResult(models.StatusDown, format, args...)
+}
+
+func unknown(format string, args ...interface{}) models.CheckResult {
+	return newResult(models.StatusUnknown, format, args...)
+}
+
+// parseAddress extracts the host and port from a target's uri, which is
+// either a URL such as smtp://mail.example.com:25 or a bare host[:port].
+// The scheme is returned so kinds can vary their behaviour with it.
+func parseAddress(uri string, defaultPort string) (scheme, host, port string, err error) {
+	if strings.Contains(uri, "://") {
+		u, err := url.Parse(uri)
+		if err != nil {
+			return "", "", "", err
+		}
+
+		scheme = u.Scheme
+		uri = u.Host
+	}
+
+	host, port, err = net.SplitHostPort(uri)
+	if err != nil {
+		host, port = strings.Trim(uri, "[]"), defaultPort
+	}
+
+	if host == "" {
+		return "", "", "", fmt.Errorf("missing host")
+	}
+
+	return scheme, host, port, nil
+}
@@ -0,0 +1,80 @@
+package checks
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"database/sql"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/tehlordvortex/updawg/database"
+	"github.com/tehlordvortex/updawg/models"
+)
+
+// newTestDb returns a migrated database which is removed after the test.
+func newTestDb(t *testing.T) *sql.DB {
+	t.Helper()
+	t.Setenv("UPDAWG_DB", filepath.Join(t.TempDir(), "test.db"))
+
+	db := database.Connect(context.Background())
+	t.Cleanup(func() { database.Close(db) })
+
+	return db
+}
+
+// newTestTarget saves a heartbeat target with the given status.
+func newTestTarget(t *testing.T, db *sql.DB, status models.Status) models.Target {
+	t.Helper()
+
+	target := models.Target{Kind: models.TargetKindHeartbeat}
+	if err := target.Save(context.Background(), db); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := target.UpdateStatus(context.Background(), db, status); err != nil {
+		t.Fatal(err)
+	}
+
+	return target
+}
+
+// newTestCert returns a self-signed certificate for localhost expiring at
+// notAfter, along with the certificate in PEM form to trust it.
+func newTestCert(t *testing.T, notAfter time.Time) (tls.Certificate, []byte) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "updawg test"},
+		NotBefore:             notAfter.Add(-365 * 24 * time.Hour),
+		NotAfter:              notAfter,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		DNSNames:              []string{"localhost"},
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert := tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+	return cert, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
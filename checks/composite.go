@@ -0,0 +1,123 @@
+package checks
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/tehlordvortex/updawg/models"
+)
+
+const (
+	CompositeModeAll      = "all"
+	CompositeModeAny      = "any"
+	CompositeModeQuorum   = "quorum"
+	CompositeModeWeighted = "weighted"
+)
+
+type compositeConfig struct {
+	Members []string `json:"members"`
+	Mode    string   `json:"mode"`
+	// Quorum is the number of members which must be up in quorum mode.
+	Quorum int `json:"quorum"`
+	// Weights default to 1 for members not listed. In weighted mode the
+	// composite is up when the up members' share of the total weight is at
+	// least Threshold.
+	Weights   map[string]float64 `json:"weights"`
+	Threshold float64            `json:"threshold"`
+}
+
+// CompositeMembers returns the ids of the targets a composite target is
+// derived from, so workers know which status changes to react to.
+func CompositeMembers(target *models.Target) []string {
+	var cfg compositeConfig
+	if err := target.DecodeConfig(&cfg); err != nil {
+		return nil
+	}
+
+	return slices.DeleteFunc(cfg.Members, func(id string) bool {
+		return id == target.Id()
+	})
+}
+
+// checkComposite derives a composite target's status from the current status
+// of its members. When not up, it is degraded while any member is still up
+// and down otherwise.
+func checkComposite(ctx context.Context, qe models.QueryExecutor, target *models.Target) models.CheckResult {
+	cfg := compositeConfig{Mode: CompositeModeAll, Threshold: 1}
+	if err := target.DecodeConfig(&cfg); err != nil {
+		return unknown("invalid config: %v", err)
+	}
+
+	members, err := models.FindTargetsByIds(ctx, qe, CompositeMembers(target))
+	if err != nil {
+		return unknown("failed to load members: %v", err)
+	}
+
+	if len(members) == 0 {
+		return unknown("no members")
+	}
+
+	statuses := make(map[string]string)
+	var upCount, unknownCount int
+	var upWeight, totalWeight float64
+
+	for _, member := range members {
+		weight, ok := cfg.Weights[member.Id()]
+		if !ok {
+			weight = 1
+		}
+
+		totalWeight += weight
+		statuses[member.Id()] = string(member.Status())
+
+		switch member.Status() {
+		case models.StatusUp:
+			upCount++
+			upWeight += weight
+		case models.StatusUnknown:
+			unknownCount++
+		}
+	}
+
+	var healthy bool
+	switch cfg.Mode {
+	case CompositeModeAll:
+		healthy = upCount == len(members)
+	case CompositeModeAny:
+		healthy = upCount > 0
+	case CompositeModeQuorum:
+		if cfg.Quorum <= 0 {
+			return unknown("invalid config: quorum must be positive")
+		}
+
+		healthy = upCount >= cfg.Quorum
+	case CompositeModeWeighted:
+		healthy = totalWeight > 0 && upWeight/totalWeight >= cfg.Threshold
+	default:
+		return unknown("invalid config: unknown mode %s", cfg.Mode)
+	}
+
+	summary := fmt.Sprintf("%d/%d members up", upCount, len(members))
+
+	var result models.CheckResult
+	switch {
+	case healthy:
+		result = up("%s", summary)
+	case unknownCount == len(members):
+		result = unknown("%s", summary)
+	case upCount > 0:
+		result = degraded("%s", summary)
+	default:
+		result = down("%s", summary)
+	}
+
+	result.Data["mode"] = cfg.Mode
+	result.Data["members"] = statuses
+	if cfg.Mode == CompositeModeWeighted {
+		result.Data["up_weight"] = upWeight
+		result.Data["total_weight"] = totalWeight
+	}
+
+	return result
+}
@@ -0,0 +1,124 @@
+package checks
+
+import (
+	"context"
+	"testing"
+
+	"github.com/tehlordvortex/updawg/models"
+)
+
+func TestCheckComposite(t *testing.T) {
+	db := newTestDb(t)
+
+	up1 := newTestTarget(t, db, models.StatusUp)
+	up2 := newTestTarget(t, db, models.StatusUp)
+	down := newTestTarget(t, db, models.StatusDown)
+	unknown1 := newTestTarget(t, db, models.StatusUnknown)
+	unknown2 := newTestTarget(t, db, models.StatusUnknown)
+
+	tests := []struct {
+		name   string
+		config map[string]interface{}
+		want   models.Status
+	}{
+		{
+			name:   "all up",
+			config: map[string]interface{}{"members": []string{up1.Id(), up2.Id()}},
+			want:   models.StatusUp,
+		},
+		{
+			name:   "all with one down",
+			config: map[string]interface{}{"members": []string{up1.Id(), up2.Id(), down.Id()}, "mode": CompositeModeAll},
+			want:   models.StatusDegraded,
+		},
+		{
+			name:   "any with one up",
+			config: map[string]interface{}{"members": []string{up1.Id(), down.Id()}, "mode": CompositeModeAny},
+			want:   models.StatusUp,
+		},
+		{
+			name:   "any with none up",
+			config: map[string]interface{}{"members": []string{down.Id(), unknown1.Id()}, "mode": CompositeModeAny},
+			want:   models.StatusDown,
+		},
+		{
+			name:   "quorum met",
+			config: map[string]interface{}{"members": []string{up1.Id(), up2.Id(), down.Id()}, "mode": CompositeModeQuorum, "quorum": 2},
+			want:   models.StatusUp,
+		},
+		{
+			name:   "quorum missed",
+			config: map[string]interface{}{"members": []string{up1.Id(), down.Id(), unknown1.Id()}, "mode": CompositeModeQuorum, "quorum": 2},
+			want:   models.StatusDegraded,
+		},
+		{
+			name:   "quorum not set",
+			config: map[string]interface{}{"members": []string{up1.Id()}, "mode": CompositeModeQuorum},
+			want:   models.StatusUnknown,
+		},
+		{
+			name: "weighted above threshold",
+			config: map[string]interface{}{
+				"members": []string{up1.Id(), down.Id()}, "mode": CompositeModeWeighted,
+				"weights": map[string]float64{up1.Id(): 3}, "threshold": 0.75,
+			},
+			want: models.StatusUp,
+		},
+		{
+			name: "weighted below threshold",
+			config: map[string]interface{}{
+				"members": []string{up1.Id(), down.Id()}, "mode": CompositeModeWeighted,
+				"weights": map[string]float64{down.Id(): 3}, "threshold": 0.5,
+			},
+			want: models.StatusDegraded,
+		},
+		{
+			name:   "weighted defaults to every member",
+			config: map[string]interface{}{"members": []string{up1.Id(), down.Id()}, "mode": CompositeModeWeighted},
+			want:   models.StatusDegraded,
+		},
+		{
+			name:   "every member unknown",
+			config: map[string]interface{}{"members": []string{unknown1.Id(), unknown2.Id()}},
+			want:   models.StatusUnknown,
+		},
+		{
+			name:   "unknown mode",
+			config: map[string]interface{}{"members": []string{up1.Id()}, "mode": "majority"},
+			want:   models.StatusUnknown,
+		},
+		{
+			name:   "no members",
+			config: map[string]interface{}{"members": []string{"target_missing"}},
+			want:   models.StatusUnknown,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := models.Target{Kind: models.TargetKindComposite}
+			target.SetConfig(tt.config)
+
+			result := checkComposite(context.Background(), db, &target)
+			if result.Status != tt.want {
+				t.Errorf("status = %s, want %s (message %q)", result.Status, tt.want, result.Message)
+			}
+		})
+	}
+}
+
+func TestCompositeMembers(t *testing.T) {
+	db := newTestDb(t)
+
+	target := models.Target{Kind: models.TargetKindComposite}
+	if err := target.Save(context.Background(), db); err != nil {
+		t.Fatal(err)
+	}
+
+	target.SetConfig(map[string]interface{}{"members": []string{"target_a", target.Id(), "target_b"}})
+
+	members := CompositeMembers(&target)
+	if len(members) != 2 || members[0] != "target_a" || members[1] != "target_b" {
+		t.Errorf("CompositeMembers() = %v, want the members other than the target itself", members)
+	}
+}
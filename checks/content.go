@@ -0,0 +1,239 @@
+package checks
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+
+	"github.com/tehlordvortex/updawg/config"
+	"github.com/tehlordvortex/updawg/models"
+)
+
+const maxContentBodySize = 4 << 20
+
+var (
+	htmlIgnoredElements = regexp.MustCompile(`(?is)<(script|style|noscript)\b.*?</(script|style|noscript)>|<!--.*?-->`)
+	htmlBlockTags       = regexp.MustCompile(`(?i)<(br|/p|/div|/li|/tr|/h[1-6]|/section|/article|/header|/footer)\b[^>]*>`)
+	htmlTags            = regexp.MustCompile(`(?s)<[^>]*>`)
+	contentWhitespace   = regexp.MustCompile(`[ \t\r\f\v]+`)
+)
+
+type contentConfig struct {
+	// Selector limits the content to the first element matching a simple
+	// selector: tag, #id, .class, tag#id or tag.class.
+	Selector string `json:"selector"`
+	// Regex limits the content to its first match, or its first capture
+	// group if it has one.
+	Regex string `json:"regex"`
+	// Raw disables stripping HTML tags before hashing.
+	Raw bool `json:"raw"`
+}
+
+// checkContent fetches the target's uri and stores a snapshot whenever the
+// normalized content changes. The target is only down if it can't be
+// fetched.
+func checkContent(ctx context.Context, qe models.QueryExecutor, target *models.Target) models.CheckResult {
+	var cfg contentConfig
+	if err := target.DecodeConfig(&cfg); err != nil {
+		return unknown("invalid config: %v", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target.Uri, nil)
+	if err != nil {
+		return unknown("%v", err)
+	}
+
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return down("%v", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != config.DefaultResponseCode {
+		result := down("unexpected status %d", res.StatusCode)
+		result.Data["status_code"] = res.StatusCode
+
+		return result
+	}
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, maxContentBodySize))
+	if err != nil {
+		return down("%v", err)
+	}
+
+	content, err := selectContent(string(body), cfg)
+	if err != nil {
+		return down("%v", err)
+	}
+
+	if !cfg.Raw {
+		content = normalizeHtml(content)
+	}
+
+	sum := sha256.Sum256([]byte(content))
+	hash := hex.EncodeToString(sum[:])
+
+	previous, err := models.FindLatestContentSnapshotByTargetId(ctx, qe, target.Id())
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return unknown("failed to load snapshot: %v", err)
+	}
+
+	var result models.CheckResult
+	if previous.Hash == hash {
+		result = up("content unchanged")
+	} else {
+		snapshot := models.ContentSnapshot{TargetId: target.Id(), Hash: hash, Content: content}
+		if previous.Id() != "" {
+			snapshot.Diff = diffLines(previous.Content, content)
+		}
+
+		if err := snapshot.Save(ctx, qe); err != nil {
+			return unknown("failed to save snapshot: %v", err)
+		}
+
+		if previous.Id() != "" {
+			result = up("content changed")
+			result.Data["changed"] = true
+		} else {
+			result = up("content recorded")
+		}
+		result.Data["snapshot_id"] = snapshot.Id()
+	}
+
+	result.Data["status_code"] = res.StatusCode
+	result.Data["hash"] = hash
+
+	return result
+}
+
+func selectContent(body string, cfg contentConfig) (string, error) {
+	if cfg.Selector != "" {
+		element, err := selectElement(body, cfg.Selector)
+		if err != nil {
+			return "", err
+		}
+
+		body = element
+	}
+
+	if cfg.Regex != "" {
+		re, err := regexp.Compile(cfg.Regex)
+		if err != nil {
+			return "", fmt.Errorf("invalid regex: %v", err)
+		}
+
+		match := re.FindStringSubmatch(body)
+		if match == nil {
+			return "", fmt.Errorf("%q did not match", cfg.Regex)
+		}
+
+		// Use the first capture group, or the whole match without one
+		if len(match) > 1 {
+			body = match[1]
+		} else {
+			body = match[0]
+		}
+	}
+
+	return body, nil
+}
+
+// normalizeHtml reduces HTML to its visible text, one block per line with
+// whitespace collapsed, so markup and formatting changes are ignored.
+func normalizeHtml(content string) string {
+	content = htmlIgnoredElements.ReplaceAllString(content, "")
+	content = htmlBlockTags.ReplaceAllString(content, "\n")
+	content = htmlTags.ReplaceAllString(content, "")
+	content = html.UnescapeString(content)
+
+	var lines []string
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimSpace(contentWhitespace.ReplaceAllString(line, " "))
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// selectElement returns the outer HTML of the first element matching a
+// simple selector. Elements are matched by scanning tags, so it copes with
+// nesting of the same tag but not with malformed markup.
+func selectElement(body string, selector string) (string, error) {
+	tag, id, class := parseSelector(selector)
+	if tag == "" && id == "" && class == "" {
+		return "", fmt.Errorf("invalid selector: %s", selector)
+	}
+
+	tagPattern := `[a-zA-Z][a-zA-Z0-9-]*`
+	if tag != "" {
+		tagPattern = regexp.QuoteMeta(tag)
+	}
+
+	var attrPattern string
+	if id != "" {
+		attrPattern = `[^>]*\bid\s*=\s*["']?` + regexp.QuoteMeta(id) + `["'\s>]`
+	} else if class != "" {
+		attrPattern = `[^>]*\bclass\s*=\s*["'][^"']*\b` + regexp.QuoteMeta(class) + `\b`
+	}
+
+	start := regexp.MustCompile(`(?i)<(` + tagPattern + `)\b` + attrPattern)
+	loc := start.FindStringSubmatchIndex(body)
+	if loc == nil {
+		return "", fmt.Errorf("selector %s did not match", selector)
+	}
+
+	name := body[loc[2]:loc[3]]
+	tags := regexp.MustCompile(`(?i)<(/?)` + regexp.QuoteMeta(name) + `\b[^>]*?(/?)>`)
+
+	depth := 0
+	for _, m := range tags.FindAllStringSubmatchIndex(body[loc[0]:], -1) {
+		closing := m[3] > m[2]
+		selfClosing := m[5] > m[4]
+
+		if selfClosing && depth == 0 {
+			return body[loc[0] : loc[0]+m[1]], nil
+		} else if selfClosing {
+			continue
+		}
+
+		if closing {
+			depth--
+		} else {
+			depth++
+		}
+
+		if depth == 0 {
+			return body[loc[0] : loc[0]+m[1]], nil
+		}
+	}
+
+	// Unclosed, take everything after the start tag
+	return body[loc[0]:], nil
+}
+
+func parseSelector(selector string) (tag, id, class string) {
+	selector = strings.TrimSpace(selector)
+
+	if i := strings.IndexAny(selector, "#."); i != -1 {
+		tag = selector[:i]
+		if selector[i] == '#' {
+			id = selector[i+1:]
+		} else {
+			class = selector[i+1:]
+		}
+	} else {
+		tag = selector
+	}
+
+	return tag, id, class
+}
@@ -0,0 +1,53 @@
+package checks
+
+import "testing"
+
+func TestSelectContent(t *testing.T) {
+	body := `<html><body>
+<div id="header">Menu</div>
+<div class="main content"><div>Price: $10</div><p>In stock</p></div>
+<img class="logo" src="logo.png"/>
+</body></html>`
+
+	tests := []struct {
+		name    string
+		cfg     contentConfig
+		want    string
+		wantErr bool
+	}{
+		{name: "no selection", cfg: contentConfig{}, want: body},
+		{name: "id", cfg: contentConfig{Selector: "#header"}, want: `<div id="header">Menu</div>`},
+		{name: "tag and class with nesting", cfg: contentConfig{Selector: "div.content"}, want: `<div class="main content"><div>Price: $10</div><p>In stock</p></div>`},
+		{name: "tag", cfg: contentConfig{Selector: "p"}, want: `<p>In stock</p>`},
+		{name: "self closing", cfg: contentConfig{Selector: ".logo"}, want: `<img class="logo" src="logo.png"/>`},
+		{name: "selector no match", cfg: contentConfig{Selector: "#footer"}, wantErr: true},
+		{name: "invalid selector", cfg: contentConfig{Selector: " "}, wantErr: true},
+		{name: "regex whole match", cfg: contentConfig{Regex: `\$\d+`}, want: "$10"},
+		{name: "regex first capture group", cfg: contentConfig{Regex: `Price: \$(\d+)(<)`}, want: "10"},
+		{name: "selector then regex", cfg: contentConfig{Selector: "#header", Regex: `>(\w+)<`}, want: "Menu"},
+		{name: "regex no match", cfg: contentConfig{Regex: `Sold out`}, wantErr: true},
+		{name: "invalid regex", cfg: contentConfig{Regex: `(`}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := selectContent(body, tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("selectContent() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Errorf("selectContent() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeHtml(t *testing.T) {
+	got := normalizeHtml("<p>Hello   <b>world</b></p><script>var x = 1;</script><!-- hidden -->\n\n<div>Fish &amp; chips</div>")
+	want := "Hello world\nFish & chips"
+
+	if got != want {
+		t.Errorf("normalizeHtml() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,45 @@
+package checks
+
+import (
+	"context"
+
+	"github.com/tehlordvortex/updawg/models"
+)
+
+// ApplyDependencies marks a failed result as unreachable if any of the
+// target's parents are down. Parents which are themselves unreachable don't
+// count, so an outage is only alerted on at the top of the chain rather than
+// suppressed along it. The original status is kept in the result's data.
+func ApplyDependencies(ctx context.Context, qe models.QueryExecutor, target *models.Target, result models.CheckResult) models.CheckResult {
+	if len(target.ParentIds) == 0 || !result.Status.IsFailure() {
+		return result
+	}
+
+	parents, err := models.FindTargetsByIds(ctx, qe, target.ParentIds)
+	if err != nil {
+		logger.Printf("failed to load parents id=%s error=%v\n", target.Id(), err)
+		return result
+	}
+
+	var downParents []string
+	for _, parent := range parents {
+		if parent.Status() == models.StatusDown {
+			downParents = append(downParents, parent.Id())
+		}
+	}
+
+	if len(downParents) == 0 {
+		return result
+	}
+
+	if result.Data == nil {
+		result.Data = make(map[string]interface{})
+	}
+
+	result.Data["dependency_down"] = downParents
+	result.Data["original_status"] = result.Status
+	result.Status = models.StatusUnreachable
+	result.Message = "unreachable (dependency down): " + result.Message
+
+	return result
+}
@@ -0,0 +1,87 @@
+package checks
+
+import (
+	"context"
+	"testing"
+
+	"github.com/tehlordvortex/updawg/models"
+)
+
+func TestApplyDependencies(t *testing.T) {
+	db := newTestDb(t)
+
+	upParent := newTestTarget(t, db, models.StatusUp)
+	downParent := newTestTarget(t, db, models.StatusDown)
+	degradedParent := newTestTarget(t, db, models.StatusDegraded)
+	unreachableParent := newTestTarget(t, db, models.StatusUnreachable)
+	unknownParent := newTestTarget(t, db, models.StatusUnknown)
+
+	tests := []struct {
+		name    string
+		parents []string
+		status  models.Status
+		want    models.Status
+	}{
+		{name: "no parents", status: models.StatusDown, want: models.StatusDown},
+		{name: "parent down", parents: []string{downParent.Id()}, status: models.StatusDown, want: models.StatusUnreachable},
+		{name: "degraded child of down parent", parents: []string{downParent.Id()}, status: models.StatusDegraded, want: models.StatusUnreachable},
+		{name: "one of several parents down", parents: []string{upParent.Id(), downParent.Id()}, status: models.StatusDown, want: models.StatusUnreachable},
+		{name: "parent up", parents: []string{upParent.Id()}, status: models.StatusDown, want: models.StatusDown},
+		{name: "parent degraded", parents: []string{degradedParent.Id()}, status: models.StatusDown, want: models.StatusDown},
+		{name: "parent unreachable", parents: []string{unreachableParent.Id()}, status: models.StatusDown, want: models.StatusDown},
+		{name: "parent unknown", parents: []string{unknownParent.Id()}, status: models.StatusDown, want: models.StatusDown},
+		{name: "healthy child", parents: []string{downParent.Id()}, status: models.StatusUp, want: models.StatusUp},
+		{name: "unknown child", parents: []string{downParent.Id()}, status: models.StatusUnknown, want: models.StatusUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := models.Target{Kind: models.TargetKindHeartbeat, ParentIds: tt.parents}
+			result := models.CheckResult{Status: tt.status, Message: "failed"}
+
+			got := ApplyDependencies(context.Background(), db, &target, result)
+			if got.Status != tt.want {
+				t.Fatalf("status = %s, want %s", got.Status, tt.want)
+			}
+
+			if tt.want != models.StatusUnreachable {
+				return
+			}
+
+			if got.Data["original_status"] != tt.status {
+				t.Errorf("original_status = %v, want %s", got.Data["original_status"], tt.status)
+			}
+
+			if downParents, _ := got.Data["dependency_down"].([]string); len(downParents) != 1 || downParents[0] != downParent.Id() {
+				t.Errorf("dependency_down = %v, want [%s]", got.Data["dependency_down"], downParent.Id())
+			}
+		})
+	}
+}
+
+func TestSaveRejectsDependencyCycles(t *testing.T) {
+	db := newTestDb(t)
+	ctx := context.Background()
+
+	a := newTestTarget(t, db, models.StatusUp)
+
+	b := models.Target{Kind: models.TargetKindHeartbeat, ParentIds: []string{a.Id()}}
+	if err := b.Save(ctx, db); err != nil {
+		t.Fatal(err)
+	}
+
+	c := models.Target{Kind: models.TargetKindHeartbeat, ParentIds: []string{b.Id()}}
+	if err := c.Save(ctx, db); err != nil {
+		t.Fatal(err)
+	}
+
+	a.ParentIds = []string{c.Id()}
+	if err := a.Save(ctx, db); err == nil {
+		t.Errorf("saving a -> c -> b -> a succeeded, want a cycle error")
+	}
+
+	a.ParentIds = []string{a.Id()}
+	if err := a.Save(ctx, db); err == nil {
+		t.Errorf("saving a target depending on itself succeeded, want a cycle error")
+	}
+}
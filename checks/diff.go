@@ -0,0 +1,90 @@
+package checks
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxDiffCells bounds the size of the table used to compute a diff. Larger
+// changes are reported as every old line removed and every new line added.
+const maxDiffCells = 4_000_000
+
+// diffLines returns a line based diff of a and b, with removed lines prefixed
+// by "-", added lines by "+" and each hunk introduced by the line number in
+// b it applies to.
+func diffLines(a, b string) string {
+	oldLines := strings.Split(a, "\n")
+	newLines := strings.Split(b, "\n")
+
+	prefix := 0
+	for prefix < len(oldLines) && prefix < len(newLines) && oldLines[prefix] == newLines[prefix] {
+		prefix++
+	}
+
+	suffix := 0
+	for suffix < len(oldLines)-prefix && suffix < len(newLines)-prefix &&
+		oldLines[len(oldLines)-1-suffix] == newLines[len(newLines)-1-suffix] {
+		suffix++
+	}
+
+	oldLines = oldLines[prefix : len(oldLines)-suffix]
+	newLines = newLines[prefix : len(newLines)-suffix]
+
+	var sb strings.Builder
+	inHunk := false
+	emit := func(op byte, line string, newIndex int) {
+		if !inHunk {
+			fmt.Fprintf(&sb, "@@ %d @@\n", prefix+newIndex+1)
+			inHunk = true
+		}
+		sb.WriteByte(op)
+		sb.WriteString(line)
+		sb.WriteByte('\n')
+	}
+
+	if len(oldLines)*len(newLines) > maxDiffCells {
+		for _, line := range oldLines {
+			emit('-', line, 0)
+		}
+		for _, line := range newLines {
+			emit('+', line, 0)
+		}
+
+		return sb.String()
+	}
+
+	// lcs[i][j] is the length of the longest common subsequence of
+	// oldLines[i:] and newLines[j:]
+	lcs := make([][]int, len(oldLines)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(newLines)+1)
+	}
+
+	for i := len(oldLines) - 1; i >= 0; i-- {
+		for j := len(newLines) - 1; j >= 0; j-- {
+			if oldLines[i] == newLines[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(oldLines) || j < len(newLines) {
+		switch {
+		case i < len(oldLines) && j < len(newLines) && oldLines[i] == newLines[j]:
+			inHunk = false
+			i++
+			j++
+		case i < len(oldLines) && (j == len(newLines) || lcs[i+1][j] >= lcs[i][j+1]):
+			emit('-', oldLines[i], j)
+			i++
+		default:
+			emit('+', newLines[j], j)
+			j++
+		}
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,75 @@
+package checks
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestDiffLines(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want string
+	}{
+		{
+			name: "unchanged",
+			a:    "a\nb",
+			b:    "a\nb",
+			want: "",
+		},
+		{
+			name: "changed line",
+			a:    "a\nb\nc",
+			b:    "a\nB\nc",
+			want: "@@ 2 @@\n-b\n+B\n",
+		},
+		{
+			name: "appended line",
+			a:    "a\nb",
+			b:    "a\nb\nc",
+			want: "@@ 3 @@\n+c\n",
+		},
+		{
+			name: "removed first line",
+			a:    "a\nb\nc",
+			b:    "b\nc",
+			want: "@@ 1 @@\n-a\n",
+		},
+		{
+			name: "separate hunks",
+			a:    "1\n2\n3\n4\n5",
+			b:    "1\nX\n3\n4\nY",
+			want: "@@ 2 @@\n-2\n+X\n@@ 5 @@\n-5\n+Y\n",
+		},
+		{
+			name: "from empty",
+			a:    "",
+			b:    "new",
+			want: "@@ 1 @@\n-\n+new\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := diffLines(tt.a, tt.b); got != tt.want {
+				t.Errorf("diffLines(%q, %q) = %q, want %q", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffLinesTooLarge(t *testing.T) {
+	var oldLines, newLines []string
+	for i := 0; i < 2001; i++ {
+		oldLines = append(oldLines, "old "+strconv.Itoa(i))
+		newLines = append(newLines, "new "+strconv.Itoa(i))
+	}
+
+	got := diffLines(strings.Join(oldLines, "\n"), strings.Join(newLines, "\n"))
+	lines := strings.Split(strings.TrimSuffix(got, "\n"), "\n")
+
+	if lines[0] != "@@ 1 @@" || lines[1] != "-old 0" || lines[2002] != "+new 0" || len(lines) != 1+2*2001 {
+		t.Errorf("diffLines() of large change = %d lines starting %q, want every old line removed then every new line added", len(lines), lines[:3])
+	}
+}
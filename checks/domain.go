@@ -0,0 +1,273 @@
+package checks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tehlordvortex/updawg/config"
+	"github.com/tehlordvortex/updawg/models"
+)
+
+const (
+	DefaultRdapBootstrapUrl = "https://data.iana.org/rdap/dns.json"
+	DefaultDomainWarnDays   = 30
+
+	rdapBootstrapTtl = 24 * time.Hour
+)
+
+// secondLevelLabels are labels which, directly below a two letter country
+// code, are treated as part of the public suffix, as in example.co.uk.
+var secondLevelLabels = []string{"ac", "co", "com", "edu", "gov", "net", "org"}
+
+var rdapBootstrap = struct {
+	sync.Mutex
+	services  map[string]string
+	fetchedAt time.Time
+}{}
+
+type domainConfig struct {
+	// Domain overrides the registrable domain derived from the uri.
+	Domain string `json:"domain"`
+	// RdapUrl is the base URL of the RDAP server to query. When empty it is
+	// found through the IANA bootstrap registry.
+	RdapUrl  string `json:"rdap_url"`
+	WarnDays int64  `json:"warn_days"`
+	Timeout  int64  `json:"timeout"`
+}
+
+type rdapDomain struct {
+	LdhName string `json:"ldhName"`
+	Events  []struct {
+		Action string `json:"eventAction"`
+		Date   string `json:"eventDate"`
+	} `json:"events"`
+	Entities []struct {
+		Roles      []string        `json:"roles"`
+		VcardArray json.RawMessage `json:"vcardArray"`
+	} `json:"entities"`
+}
+
+// checkDomain looks up the registration of the target's domain over RDAP,
+// degrading warn_days before it expires.
+func checkDomain(ctx context.Context, qe models.QueryExecutor, target *models.Target) models.CheckResult {
+	cfg := domainConfig{WarnDays: DefaultDomainWarnDays}
+	if err := target.DecodeConfig(&cfg); err != nil {
+		return unknown("invalid config: %v", err)
+	}
+
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = config.DefaultTimeout
+	}
+
+	domain := cfg.Domain
+	if domain == "" {
+		_, host, _, err := parseAddress(target.Uri, "")
+		if err != nil {
+			return unknown("invalid uri: %v", err)
+		}
+
+		domain = registrableDomain(host)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(cfg.Timeout)*time.Second)
+	defer cancel()
+
+	rdapUrl := cfg.RdapUrl
+	if rdapUrl == "" {
+		var err error
+		if rdapUrl, err = findRdapServer(ctx, domain); err != nil {
+			return unknown("%v", err)
+		}
+	}
+
+	registration, err := fetchRdapDomain(ctx, rdapUrl, domain)
+	if err != nil {
+		return unknown("rdap lookup failed: %v", err)
+	}
+
+	var expiresAt time.Time
+	for _, event := range registration.Events {
+		if event.Action == "expiration" {
+			expiresAt, err = time.Parse(time.RFC3339, event.Date)
+			if err != nil {
+				return unknown("invalid expiration date %s: %v", event.Date, err)
+			}
+		}
+	}
+
+	registrar := rdapRegistrar(registration)
+
+	var result models.CheckResult
+	if expiresAt.IsZero() {
+		result = unknown("no expiration date for %s", domain)
+	} else {
+		daysRemaining := int64(time.Until(expiresAt).Hours() / 24)
+
+		switch {
+		case !expiresAt.After(time.Now()):
+			result = down("%s expired on %s", domain, expiresAt.Format(time.DateOnly))
+		case daysRemaining < cfg.WarnDays:
+			result = degraded("%s expires in %d days", domain, daysRemaining)
+		default:
+			result = up("%s expires in %d days", domain, daysRemaining)
+		}
+
+		result.Data["expires_at"] = expiresAt.Unix()
+		result.Data["days_remaining"] = daysRemaining
+	}
+
+	result.Data["domain"] = domain
+	result.Data["rdap_url"] = rdapUrl
+	if registrar != "" {
+		result.Data["registrar"] = registrar
+	}
+
+	return result
+}
+
+// registrableDomain approximates the registrable part of a hostname without
+// the public suffix list, keeping one label below the suffix.
+func registrableDomain(host string) string {
+	labels := strings.Split(strings.TrimSuffix(strings.ToLower(host), "."), ".")
+	if len(labels) <= 2 {
+		return strings.Join(labels, ".")
+	}
+
+	keep := 2
+	tld, sld := labels[len(labels)-1], labels[len(labels)-2]
+	if len(tld) == 2 && slices.Contains(secondLevelLabels, sld) {
+		keep = 3
+	}
+
+	return strings.Join(labels[len(labels)-keep:], ".")
+}
+
+func findRdapServer(ctx context.Context, domain string) (string, error) {
+	rdapBootstrap.Lock()
+	services := rdapBootstrap.services
+	stale := services == nil || time.Since(rdapBootstrap.fetchedAt) > rdapBootstrapTtl
+	rdapBootstrap.Unlock()
+
+	// Fetch without holding the lock so checks aren't queued behind a slow
+	// registry. Concurrent fetches are harmless, the last one wins.
+	if stale {
+		var err error
+		if services, err = fetchRdapBootstrap(ctx); err != nil {
+			return "", fmt.Errorf("rdap bootstrap failed: %v", err)
+		}
+
+		rdapBootstrap.Lock()
+		rdapBootstrap.services = services
+		rdapBootstrap.fetchedAt = time.Now()
+		rdapBootstrap.Unlock()
+	}
+
+	tld := domain[strings.LastIndex(domain, ".")+1:]
+	server, ok := services[tld]
+	if !ok {
+		return "", fmt.Errorf("no rdap server for .%s", tld)
+	}
+
+	return server, nil
+}
+
+// fetchRdapBootstrap returns the RDAP server for each TLD in the IANA
+// bootstrap registry (RFC 9224).
+func fetchRdapBootstrap(ctx context.Context) (map[string]string, error) {
+	var bootstrap struct {
+		Services [][][]string `json:"services"`
+	}
+
+	if err := getJson(ctx, DefaultRdapBootstrapUrl, &bootstrap); err != nil {
+		return nil, err
+	}
+
+	services := make(map[string]string)
+	for _, service := range bootstrap.Services {
+		if len(service) != 2 || len(service[1]) == 0 {
+			continue
+		}
+
+		for _, tld := range service[0] {
+			services[strings.ToLower(tld)] = service[1][0]
+		}
+	}
+
+	return services, nil
+}
+
+func fetchRdapDomain(ctx context.Context, rdapUrl, domain string) (rdapDomain, error) {
+	var registration rdapDomain
+
+	base, err := url.Parse(rdapUrl)
+	if err != nil {
+		return registration, err
+	}
+
+	if !strings.HasSuffix(base.Path, "/") {
+		base.Path += "/"
+	}
+
+	lookupUrl := base.JoinPath("domain", domain).String()
+	err = getJson(ctx, lookupUrl, &registration)
+
+	return registration, err
+}
+
+// rdapRegistrar returns the formatted name from the registrar entity's
+// jCard, if there is one.
+func rdapRegistrar(registration rdapDomain) string {
+	for _, entity := range registration.Entities {
+		if !slices.Contains(entity.Roles, "registrar") {
+			continue
+		}
+
+		// ["vcard", [["fn", {}, "text", "Example Registrar"], ...]]
+		var vcard []json.RawMessage
+		if err := json.Unmarshal(entity.VcardArray, &vcard); err != nil || len(vcard) != 2 {
+			continue
+		}
+
+		var properties [][]interface{}
+		if err := json.Unmarshal(vcard[1], &properties); err != nil {
+			continue
+		}
+
+		for _, property := range properties {
+			if len(property) == 4 && property[0] == "fn" {
+				if name, ok := property[3].(string); ok {
+					return name
+				}
+			}
+		}
+	}
+
+	return ""
+}
+
+func getJson(ctx context.Context, url string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/rdap+json, application/json")
+
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: unexpected status %d", url, res.StatusCode)
+	}
+
+	return json.NewDecoder(res.Body).Decode(v)
+}
@@ -0,0 +1,150 @@
+package checks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/tehlordvortex/updawg/models"
+)
+
+func TestRegistrableDomain(t *testing.T) {
+	tests := []struct {
+		host string
+		want string
+	}{
+		{"example.com", "example.com"},
+		{"www.example.com", "example.com"},
+		{"a.b.example.com.", "example.com"},
+		{"WWW.Example.COM", "example.com"},
+		{"www.example.co.uk", "example.co.uk"},
+		{"shop.example.com.au", "example.com.au"},
+		{"www.example.de", "example.de"},
+		{"example.co.uk", "example.co.uk"},
+		{"localhost", "localhost"},
+	}
+
+	for _, tt := range tests {
+		if got := registrableDomain(tt.host); got != tt.want {
+			t.Errorf("registrableDomain(%q) = %q, want %q", tt.host, got, tt.want)
+		}
+	}
+}
+
+func TestRdapRegistrar(t *testing.T) {
+	tests := []struct {
+		name     string
+		entities string
+		want     string
+	}{
+		{
+			name:     "registrar",
+			entities: `[{"roles":["registrar"],"vcardArray":["vcard",[["version",{},"text","4.0"],["fn",{},"text","Example Registrar"]]]}]`,
+			want:     "Example Registrar",
+		},
+		{
+			name:     "other roles are skipped",
+			entities: `[{"roles":["registrant"],"vcardArray":["vcard",[["fn",{},"text","Someone"]]]},{"roles":["registrar"],"vcardArray":["vcard",[["fn",{},"text","Registrar"]]]}]`,
+			want:     "Registrar",
+		},
+		{
+			name:     "malformed vcard",
+			entities: `[{"roles":["registrar"],"vcardArray":"vcard"}]`,
+			want:     "",
+		},
+		{
+			name:     "no fn",
+			entities: `[{"roles":["registrar"],"vcardArray":["vcard",[["version",{},"text","4.0"]]]}]`,
+			want:     "",
+		},
+		{
+			name:     "no entities",
+			entities: `[]`,
+			want:     "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var registration rdapDomain
+			if err := json.Unmarshal([]byte(`{"entities":`+tt.entities+`}`), &registration); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := rdapRegistrar(registration); got != tt.want {
+				t.Errorf("rdapRegistrar() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckDomain(t *testing.T) {
+	day := 24 * time.Hour
+	rdapResponse := func(expiresIn time.Duration) string {
+		return fmt.Sprintf(`{"ldhName":"EXAMPLE.COM","events":[{"eventAction":"registration","eventDate":"2000-01-01T00:00:00Z"},{"eventAction":"expiration","eventDate":%q}]}`,
+			time.Now().Add(expiresIn).UTC().Format(time.RFC3339))
+	}
+
+	tests := []struct {
+		name     string
+		response string
+		status   int
+		want     models.Status
+	}{
+		{name: "far from expiry", response: rdapResponse(365 * day), status: http.StatusOK, want: models.StatusUp},
+		{name: "within warn days", response: rdapResponse(10 * day), status: http.StatusOK, want: models.StatusDegraded},
+		{name: "expired", response: rdapResponse(-day), status: http.StatusOK, want: models.StatusDown},
+		{name: "no expiration", response: `{"events":[]}`, status: http.StatusOK, want: models.StatusUnknown},
+		{name: "invalid expiration", response: `{"events":[{"eventAction":"expiration","eventDate":"soon"}]}`, status: http.StatusOK, want: models.StatusUnknown},
+		{name: "not found", response: `{}`, status: http.StatusNotFound, want: models.StatusUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var path string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				path = r.URL.Path
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.response))
+			}))
+			defer server.Close()
+
+			target := models.Target{Kind: models.TargetKindDomain, Uri: "https://www.example.com/login"}
+			target.SetConfig(map[string]interface{}{"rdap_url": server.URL + "/rdap", "warn_days": 30})
+
+			result := checkDomain(context.Background(), nil, &target)
+			if result.Status != tt.want {
+				t.Errorf("status = %s, want %s (message %q)", result.Status, tt.want, result.Message)
+			}
+
+			if path != "/rdap/domain/example.com" {
+				t.Errorf("requested %s, want /rdap/domain/example.com", path)
+			}
+		})
+	}
+}
+
+func TestFindRdapServer(t *testing.T) {
+	rdapBootstrap.Lock()
+	rdapBootstrap.services = map[string]string{"com": "https://rdap.example/com/"}
+	rdapBootstrap.fetchedAt = time.Now()
+	rdapBootstrap.Unlock()
+
+	t.Cleanup(func() {
+		rdapBootstrap.Lock()
+		rdapBootstrap.services = nil
+		rdapBootstrap.Unlock()
+	})
+
+	if got, err := findRdapServer(context.Background(), "example.com"); err != nil || got != "https://rdap.example/com/" {
+		t.Errorf("findRdapServer(example.com) = %q, %v", got, err)
+	}
+
+	if _, err := findRdapServer(context.Background(), "example.invalid"); err == nil {
+		t.Errorf("findRdapServer(example.invalid) returned no error")
+	}
+}
@@ -0,0 +1,182 @@
+package checks
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tehlordvortex/updawg/config"
+	"github.com/tehlordvortex/updawg/models"
+)
+
+type execConfig struct {
+	Args []string `json:"args"`
+	// Timeout is the number of seconds the command may run for.
+	Timeout int64 `json:"timeout"`
+}
+
+// execStatuses maps Nagios plugin exit codes to statuses.
+var execStatuses = map[int]models.Status{
+	0: models.StatusUp,
+	1: models.StatusDegraded,
+	2: models.StatusDown,
+	3: models.StatusUnknown,
+}
+
+// checkExec runs the command in the target's uri as a Nagios compatible
+// plugin.
+func checkExec(ctx context.Context, qe models.QueryExecutor, target *models.Target) models.CheckResult {
+	var cfg execConfig
+	if err := target.DecodeConfig(&cfg); err != nil {
+		return unknown("invalid config: %v", err)
+	}
+
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = config.DefaultTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(cfg.Timeout)*time.Second)
+	defer cancel()
+
+	var stdout bytes.Buffer
+	cmd := exec.CommandContext(ctx, target.Uri, cfg.Args...)
+	cmd.Stdout = &stdout
+	cmd.WaitDelay = time.Second
+
+	err := cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		return unknown("timed out after %ds", cfg.Timeout)
+	}
+
+	exitCode := 0
+	if err != nil {
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			return unknown("%v", err)
+		}
+
+		exitCode = exitErr.ExitCode()
+	}
+
+	status, ok := execStatuses[exitCode]
+	if !ok {
+		status = models.StatusUnknown
+	}
+
+	output, perfData := parsePluginOutput(stdout.String())
+
+	result := newResult(status, "%s", output)
+	result.Data["exit_code"] = exitCode
+	if perfData != "" {
+		result.Data["perfdata"] = perfData
+		result.Data["metrics"] = parsePerfData(perfData)
+	}
+
+	return result
+}
+
+// parsePluginOutput splits the first line of plugin output into the text and
+// the performance data following the pipe.
+func parsePluginOutput(stdout string) (string, string) {
+	line, _, _ := strings.Cut(stdout, "\n")
+	output, perfData, _ := strings.Cut(line, "|")
+
+	return strings.TrimSpace(output), strings.TrimSpace(perfData)
+}
+
+type perfDataMetric struct {
+	Value float64  `json:"value"`
+	Unit  string   `json:"unit,omitempty"`
+	Warn  string   `json:"warn,omitempty"`
+	Crit  string   `json:"crit,omitempty"`
+	Min   *float64 `json:"min,omitempty"`
+	Max   *float64 `json:"max,omitempty"`
+}
+
+// parsePerfData parses 'label'=value[UOM];[warn];[crit];[min];[max] entries.
+// Malformed entries are skipped.
+func parsePerfData(perfData string) map[string]perfDataMetric {
+	metrics := make(map[string]perfDataMetric)
+
+	scanner := bufio.NewScanner(strings.NewReader(perfData))
+	scanner.Split(scanPerfDataEntries)
+
+	for scanner.Scan() {
+		label, rest, found := strings.Cut(scanner.Text(), "=")
+		if !found {
+			continue
+		}
+
+		label = strings.Trim(label, "'")
+		fields := strings.Split(rest, ";")
+
+		rawValue := fields[0]
+		unitStart := strings.IndexFunc(rawValue, func(r rune) bool {
+			return !strings.ContainsRune("0123456789.-+eE", r)
+		})
+
+		var metric perfDataMetric
+		if unitStart != -1 {
+			metric.Unit = rawValue[unitStart:]
+			rawValue = rawValue[:unitStart]
+		}
+
+		value, err := strconv.ParseFloat(rawValue, 64)
+		if err != nil {
+			continue
+		}
+		metric.Value = value
+
+		field := func(i int) string {
+			if i < len(fields) {
+				return fields[i]
+			}
+
+			return ""
+		}
+
+		metric.Warn = field(1)
+		metric.Crit = field(2)
+		if min, err := strconv.ParseFloat(field(3), 64); err == nil {
+			metric.Min = &min
+		}
+		if max, err := strconv.ParseFloat(field(4), 64); err == nil {
+			metric.Max = &max
+		}
+
+		metrics[label] = metric
+	}
+
+	return metrics
+}
+
+// scanPerfDataEntries splits on spaces outside of single quoted labels.
+func scanPerfDataEntries(data []byte, atEOF bool) (int, []byte, error) {
+	start := 0
+	for start < len(data) && data[start] == ' ' {
+		start++
+	}
+
+	quoted := false
+	for i := start; i < len(data); i++ {
+		switch data[i] {
+		case '\'':
+			quoted = !quoted
+		case ' ':
+			if !quoted {
+				return i + 1, data[start:i], nil
+			}
+		}
+	}
+
+	if atEOF && start < len(data) {
+		return len(data), data[start:], nil
+	}
+
+	return start, nil, nil
+}
@@ -0,0 +1,132 @@
+package checks
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/tehlordvortex/updawg/models"
+)
+
+func TestParsePerfData(t *testing.T) {
+	float := func(v float64) *float64 { return &v }
+
+	tests := []struct {
+		name     string
+		perfData string
+		want     map[string]perfDataMetric
+	}{
+		{
+			name:     "value only",
+			perfData: "load=0.5",
+			want:     map[string]perfDataMetric{"load": {Value: 0.5}},
+		},
+		{
+			name:     "all fields",
+			perfData: "time=0.25s;1;2;0;10",
+			want: map[string]perfDataMetric{
+				"time": {Value: 0.25, Unit: "s", Warn: "1", Crit: "2", Min: float(0), Max: float(10)},
+			},
+		},
+		{
+			name:     "ranges and empty fields",
+			perfData: "users=3;@5:10;;;",
+			want:     map[string]perfDataMetric{"users": {Value: 3, Warn: "@5:10"}},
+		},
+		{
+			name:     "percent unit",
+			perfData: "disk=93.5%;80;90",
+			want:     map[string]perfDataMetric{"disk": {Value: 93.5, Unit: "%", Warn: "80", Crit: "90"}},
+		},
+		{
+			name:     "negative and exponent values",
+			perfData: "offset=-1.5e-3s",
+			want:     map[string]perfDataMetric{"offset": {Value: -1.5e-3, Unit: "s"}},
+		},
+		{
+			name:     "quoted label with spaces",
+			perfData: "'free space'=10GB 'used space'=2GB",
+			want: map[string]perfDataMetric{
+				"free space": {Value: 10, Unit: "GB"},
+				"used space": {Value: 2, Unit: "GB"},
+			},
+		},
+		{
+			name:     "repeated spaces between entries",
+			perfData: "a=1   b=2",
+			want:     map[string]perfDataMetric{"a": {Value: 1}, "b": {Value: 2}},
+		},
+		{
+			name:     "malformed entries are skipped",
+			perfData: "novalue a=U b=2 c=",
+			want:     map[string]perfDataMetric{"b": {Value: 2}},
+		},
+		{
+			name:     "empty",
+			perfData: "",
+			want:     map[string]perfDataMetric{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parsePerfData(tt.perfData); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parsePerfData(%q) = %+v, want %+v", tt.perfData, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePluginOutput(t *testing.T) {
+	tests := []struct {
+		stdout       string
+		wantOutput   string
+		wantPerfData string
+	}{
+		{"OK - all good", "OK - all good", ""},
+		{"OK - all good | load=0.5\n", "OK - all good", "load=0.5"},
+		{"WARNING - slow|time=2s\nlong output | ignored=1\n", "WARNING - slow", "time=2s"},
+		{"", "", ""},
+	}
+
+	for _, tt := range tests {
+		output, perfData := parsePluginOutput(tt.stdout)
+		if output != tt.wantOutput || perfData != tt.wantPerfData {
+			t.Errorf("parsePluginOutput(%q) = %q, %q, want %q, %q", tt.stdout, output, perfData, tt.wantOutput, tt.wantPerfData)
+		}
+	}
+}
+
+func TestCheckExecExitCodes(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		want   models.Status
+	}{
+		{"ok", "echo OK; exit 0", models.StatusUp},
+		{"warning", "echo WARNING; exit 1", models.StatusDegraded},
+		{"critical", "echo CRITICAL; exit 2", models.StatusDown},
+		{"unknown", "echo UNKNOWN; exit 3", models.StatusUnknown},
+		{"out of range", "exit 4", models.StatusUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := models.Target{Kind: models.TargetKindExec, Uri: "sh"}
+			target.SetConfig(map[string]interface{}{"args": []string{"-c", tt.script}})
+
+			result := checkExec(context.Background(), nil, &target)
+			if result.Status != tt.want {
+				t.Errorf("status = %s, want %s (message %q)", result.Status, tt.want, result.Message)
+			}
+		})
+	}
+
+	t.Run("missing command", func(t *testing.T) {
+		target := models.Target{Kind: models.TargetKindExec, Uri: "updawg-no-such-plugin"}
+
+		if result := checkExec(context.Background(), nil, &target); result.Status != models.StatusUnknown {
+			t.Errorf("status = %s, want %s", result.Status, models.StatusUnknown)
+		}
+	})
+}
@@ -0,0 +1,64 @@
+package checks
+
+import (
+	"time"
+
+	"github.com/tehlordvortex/updawg/models"
+)
+
+type heartbeatConfig struct {
+	// Grace is the number of seconds a ping may be late by before the
+	// target is considered down.
+	Grace int64 `json:"grace"`
+}
+
+// HeartbeatDeadline is how long a heartbeat target may go without a ping
+// before it is considered down.
+func HeartbeatDeadline(target *models.Target) time.Duration {
+	var cfg heartbeatConfig
+	if err := target.DecodeConfig(&cfg); err != nil {
+		logger.Printf("invalid heartbeat config id=%s error=%v\n", target.Id(), err)
+	}
+
+	return time.Duration(target.Period+max(cfg.Grace, 0)) * time.Second
+}
+
+// HeartbeatPingResult converts a ping into a check result. startedAt is the
+// time of the last start signal, if any. Start signals do not produce a
+// result.
+func HeartbeatPingResult(target *models.Target, ping models.Ping, startedAt time.Time) (models.CheckResult, bool) {
+	var result models.CheckResult
+
+	switch ping.Signal {
+	case models.PingSignalSuccess:
+		result = up("ping received")
+	case models.PingSignalFail:
+		result = down("fail signal received")
+	default:
+		return result, false
+	}
+
+	if ping.Message != "" {
+		result.Message = ping.Message
+	}
+
+	result.TargetId = target.Id()
+	result.Data["signal"] = ping.Signal
+	result.Duration = ping.Duration
+	if result.Duration == 0 && !startedAt.IsZero() {
+		result.Duration = time.Since(startedAt)
+	}
+
+	return result, true
+}
+
+// HeartbeatMissedResult is recorded when no ping arrives before the deadline.
+func HeartbeatMissedResult(target *models.Target, lastPing time.Time) models.CheckResult {
+	result := down("no ping received within %s", HeartbeatDeadline(target))
+	result.TargetId = target.Id()
+	if !lastPing.IsZero() {
+		result.Data["last_ping"] = lastPing.Unix()
+	}
+
+	return result
+}
@@ -0,0 +1,76 @@
+package checks
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/tehlordvortex/updawg/config"
+	"github.com/tehlordvortex/updawg/models"
+)
+
+const maxHttpBodySize = 4 << 20
+
+// httpClient opens a new connection for every check so each result includes
+// the DNS, connect and TLS phases. Requests for a pinned address bypass any
+// proxy so they reach that address.
+var httpClient = &http.Client{
+	Transport: &http.Transport{
+		Proxy: func(req *http.Request) (*url.URL, error) {
+			if _, ok := pinnedIp(req.Context(), req.URL.Hostname()); ok {
+				return nil, nil
+			}
+
+			return http.ProxyFromEnvironment(req)
+		},
+		DialContext:       dialContext,
+		DisableKeepAlives: true,
+	},
+}
+
+func checkHttp(ctx context.Context, qe models.QueryExecutor, target *models.Target) models.CheckResult {
+	ctx, timings := withHttpTimings(ctx)
+
+	req, err := http.NewRequestWithContext(ctx, target.Method, target.Uri, nil)
+	if err != nil {
+		return unknown("%v", err)
+	}
+
+	res, err := httpClient.Do(req)
+	if err != nil {
+		timings.done()
+
+		result := down("%v", err)
+		result.Data["timings"] = timings.data()
+
+		return result
+	}
+	defer res.Body.Close()
+
+	// Read the body so the transfer time is included
+	_, err = io.Copy(io.Discard, io.LimitReader(res.Body, maxHttpBodySize))
+	timings.done()
+	if err != nil {
+		result := down("reading body: %v", err)
+		result.Data["timings"] = timings.data()
+
+		return result
+	}
+
+	var result models.CheckResult
+	if res.StatusCode != config.DefaultResponseCode {
+		result = down("unexpected status %d", res.StatusCode)
+	} else {
+		result = up("status %d", res.StatusCode)
+	}
+
+	result.Data["status_code"] = res.StatusCode
+	result.Data["timings"] = timings.data()
+	result.Duration = timings.total
+	if res.TLS != nil {
+		result.Data["tls"] = tlsData(*res.TLS)
+	}
+
+	return result
+}
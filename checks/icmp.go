@@ -0,0 +1,282 @@
+package checks
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/tehlordvortex/updawg/models"
+)
+
+const (
+	icmpEchoRequest   = 8
+	icmpEchoReply     = 0
+	icmpv6EchoRequest = 128
+	icmpv6EchoReply   = 129
+)
+
+type icmpConfig struct {
+	Count int `json:"count"`
+	// IntervalMs is the delay between echo requests.
+	IntervalMs int64 `json:"interval_ms"`
+	// Timeout is the number of seconds to wait for a reply after the last
+	// request is sent.
+	Timeout int64 `json:"timeout"`
+	Ipv6    bool  `json:"ipv6"`
+	// Packet loss percentages at or above which the target is degraded or
+	// down.
+	DegradedLoss float64 `json:"degraded_loss"`
+	DownLoss     float64 `json:"down_loss"`
+	// Average round trip times in milliseconds at or above which the target
+	// is degraded or down. Zero disables the threshold.
+	DegradedRttMs float64 `json:"degraded_rtt_ms"`
+	DownRttMs     float64 `json:"down_rtt_ms"`
+}
+
+// checkIcmp sends a series of ICMP echo requests to the host in the target's
+// uri and records packet loss and round trip times.
+func checkIcmp(ctx context.Context, qe models.QueryExecutor, target *models.Target) models.CheckResult {
+	cfg := icmpConfig{Count: 5, IntervalMs: 1000, Timeout: 2, DegradedLoss: 20, DownLoss: 100}
+	if err := target.DecodeConfig(&cfg); err != nil {
+		return unknown("invalid config: %v", err)
+	}
+
+	if cfg.Count <= 0 || cfg.IntervalMs <= 0 || cfg.Timeout <= 0 {
+		return unknown("invalid config: count, interval_ms and timeout must be positive")
+	}
+
+	_, host, _, err := parseAddress(target.Uri, "")
+	if err != nil {
+		return unknown("invalid uri: %v", err)
+	}
+
+	ip, err := resolveIcmpHost(ctx, host, cfg.Ipv6)
+	if err != nil {
+		return down("%v", err)
+	}
+	ipv6 := ip.To4() == nil
+
+	conn, raw, err := listenIcmp(ipv6)
+	if err != nil {
+		return unknown("cannot open icmp socket: %v", err)
+	}
+	defer conn.Close()
+
+	var addr net.Addr = &net.UDPAddr{IP: ip}
+	if raw {
+		addr = &net.IPAddr{IP: ip}
+	}
+
+	interval := time.Duration(cfg.IntervalMs) * time.Millisecond
+	deadline := time.Now().Add(time.Duration(cfg.Count-1)*interval + time.Duration(cfg.Timeout)*time.Second)
+
+	ctx, cancel := context.WithDeadline(ctx, deadline)
+	defer cancel()
+
+	go func() {
+		<-ctx.Done()
+		conn.SetReadDeadline(time.Now())
+	}()
+
+	id := rand.IntN(math.MaxUint16)
+	var mu sync.Mutex
+	sent := make([]time.Time, cfg.Count)
+	var sendErr error
+
+	go func() {
+		for seq := 0; seq < cfg.Count; seq++ {
+			mu.Lock()
+			sent[seq] = time.Now()
+			_, err := conn.WriteTo(icmpEcho(ipv6, id, seq), addr)
+			if err != nil {
+				sendErr = err
+			}
+			mu.Unlock()
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(interval):
+			}
+		}
+	}()
+
+	rtts := make([]time.Duration, cfg.Count)
+	received := 0
+	buf := make([]byte, 1500)
+
+	for received < cfg.Count {
+		n, _, err := conn.ReadFrom(buf)
+		if err != nil {
+			break
+		}
+		now := time.Now()
+
+		replyId, seq, ok := parseIcmpEchoReply(ipv6, buf[:n])
+		if !ok || (raw && replyId != id) || seq >= cfg.Count || rtts[seq] != 0 {
+			continue
+		}
+
+		mu.Lock()
+		rtts[seq] = now.Sub(sent[seq])
+		mu.Unlock()
+		received++
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if received == 0 && sendErr != nil {
+		return down("send failed: %v", sendErr)
+	}
+
+	stats := icmpStats(rtts)
+	loss := 100 * float64(cfg.Count-received) / float64(cfg.Count)
+	summary := fmt.Sprintf("%d/%d received, %.0f%% loss", received, cfg.Count, loss)
+	if received > 0 {
+		summary += fmt.Sprintf(", avg %.2fms", stats.avg)
+	}
+
+	var result models.CheckResult
+	switch {
+	case loss >= cfg.DownLoss || (cfg.DownRttMs > 0 && stats.avg >= cfg.DownRttMs):
+		result = down("%s", summary)
+	case loss >= cfg.DegradedLoss || (cfg.DegradedRttMs > 0 && stats.avg >= cfg.DegradedRttMs):
+		result = degraded("%s", summary)
+	default:
+		result = up("%s", summary)
+	}
+
+	result.Data["address"] = ip.String()
+	result.Data["sent"] = cfg.Count
+	result.Data["received"] = received
+	result.Data["loss"] = loss
+	if received > 0 {
+		result.Data["rtt_min_ms"] = stats.min
+		result.Data["rtt_avg_ms"] = stats.avg
+		result.Data["rtt_max_ms"] = stats.max
+		result.Data["jitter_ms"] = stats.jitter
+	}
+
+	return result
+}
+
+func resolveIcmpHost(ctx context.Context, host string, ipv6 bool) (net.IP, error) {
+	if ip, ok := pinnedIp(ctx, host); ok {
+		return ip, nil
+	}
+
+	network := "ip4"
+	if ipv6 {
+		network = "ip6"
+	}
+
+	ips, err := net.DefaultResolver.LookupIP(ctx, network, host)
+	if err != nil {
+		return nil, err
+	}
+
+	return ips[0], nil
+}
+
+func icmpEcho(ipv6 bool, id, seq int) []byte {
+	typ := byte(icmpEchoRequest)
+	if ipv6 {
+		typ = icmpv6EchoRequest
+	}
+
+	packet := []byte{typ, 0, 0, 0, byte(id >> 8), byte(id), byte(seq >> 8), byte(seq)}
+	packet = append(packet, []byte("updawg-ping-payload-0123456789ab")...)
+
+	// The kernel computes ICMPv6 checksums
+	if !ipv6 {
+		checksum := icmpChecksum(packet)
+		packet[2], packet[3] = byte(checksum>>8), byte(checksum)
+	}
+
+	return packet
+}
+
+func parseIcmpEchoReply(ipv6 bool, packet []byte) (id, seq int, ok bool) {
+	typ := byte(icmpEchoReply)
+	if ipv6 {
+		typ = icmpv6EchoReply
+	}
+
+	if len(packet) < 8 || packet[0] != typ {
+		return 0, 0, false
+	}
+
+	return int(packet[4])<<8 | int(packet[5]), int(packet[6])<<8 | int(packet[7]), true
+}
+
+func icmpChecksum(packet []byte) uint16 {
+	var sum uint32
+	for i := 0; i+1 < len(packet); i += 2 {
+		sum += uint32(packet[i])<<8 | uint32(packet[i+1])
+	}
+
+	if len(packet)%2 == 1 {
+		sum += uint32(packet[len(packet)-1]) << 8
+	}
+
+	for sum>>16 != 0 {
+		sum = sum&0xffff + sum>>16
+	}
+
+	return ^uint16(sum)
+}
+
+type rttStats struct {
+	min, avg, max, jitter float64
+}
+
+// icmpStats summarises the received round trip times in milliseconds. Jitter
+// is the mean difference between consecutive round trip times.
+func icmpStats(rtts []time.Duration) rttStats {
+	var stats rttStats
+	var received []float64
+
+	for _, rtt := range rtts {
+		if rtt != 0 {
+			received = append(received, float64(rtt.Microseconds())/1000)
+		}
+	}
+
+	if len(received) == 0 {
+		return stats
+	}
+
+	stats.min, stats.max = received[0], received[0]
+	var sum, jitterSum float64
+	for i, ms := range received {
+		sum += ms
+		stats.min = min(stats.min, ms)
+		stats.max = max(stats.max, ms)
+
+		if i > 0 {
+			jitterSum += math.Abs(ms - received[i-1])
+		}
+	}
+
+	stats.avg = sum / float64(len(received))
+	if len(received) > 1 {
+		stats.jitter = jitterSum / float64(len(received)-1)
+	}
+
+	return stats
+}
+
+func listenIcmpRaw(ipv6 bool) (net.PacketConn, bool, error) {
+	network := "ip4:icmp"
+	if ipv6 {
+		network = "ip6:ipv6-icmp"
+	}
+
+	conn, err := net.ListenPacket(network, "")
+	return conn, true, err
+}
@@ -0,0 +1,34 @@
+package checks
+
+import (
+	"net"
+	"os"
+	"syscall"
+)
+
+// listenIcmp opens an unprivileged datagram ICMP socket, falling back to a
+// raw socket if the user is not in net.ipv4.ping_group_range. The kernel
+// rewrites the echo identifier on datagram sockets, so raw reports whether
+// replies must be matched by identifier.
+func listenIcmp(ipv6 bool) (conn net.PacketConn, raw bool, err error) {
+	family, proto, sa := syscall.AF_INET, syscall.IPPROTO_ICMP, syscall.Sockaddr(&syscall.SockaddrInet4{})
+	if ipv6 {
+		family, proto, sa = syscall.AF_INET6, syscall.IPPROTO_ICMPV6, &syscall.SockaddrInet6{}
+	}
+
+	if fd, err := syscall.Socket(family, syscall.SOCK_DGRAM, proto); err == nil {
+		if err := syscall.Bind(fd, sa); err != nil {
+			syscall.Close(fd)
+		} else {
+			f := os.NewFile(uintptr(fd), "icmp")
+			conn, err := net.FilePacketConn(f)
+			f.Close()
+
+			if err == nil {
+				return conn, false, nil
+			}
+		}
+	}
+
+	return listenIcmpRaw(ipv6)
+}
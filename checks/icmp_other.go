@@ -0,0 +1,11 @@
+//go:build !linux
+
+package checks
+
+import "net"
+
+// listenIcmp opens a raw ICMP socket. Unprivileged datagram sockets are only
+// used on Linux.
+func listenIcmp(ipv6 bool) (conn net.PacketConn, raw bool, err error) {
+	return listenIcmpRaw(ipv6)
+}
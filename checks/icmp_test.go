@@ -0,0 +1,98 @@
+package checks
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tehlordvortex/updawg/models"
+)
+
+func TestIcmpChecksum(t *testing.T) {
+	tests := []struct {
+		name   string
+		packet []byte
+		want   uint16
+	}{
+		// RFC 1071 section 3's example, whose sum is 0xddf2
+		{name: "rfc 1071", packet: []byte{0x00, 0x01, 0xf2, 0x03, 0xf4, 0xf5, 0xf6, 0xf7}, want: 0x220d},
+		{name: "echo request", packet: []byte{8, 0, 0, 0, 0x12, 0x34, 0x00, 0x01}, want: 0xe5ca},
+		{name: "odd length is padded", packet: []byte{0x01, 0x02, 0x03}, want: 0xfbfd},
+		{name: "empty", packet: nil, want: 0xffff},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := icmpChecksum(tt.packet); got != tt.want {
+				t.Errorf("icmpChecksum() = %#04x, want %#04x", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIcmpEcho(t *testing.T) {
+	packet := icmpEcho(false, 0x1234, 7)
+
+	// A packet including its checksum sums to zero
+	if sum := icmpChecksum(packet); sum != 0 {
+		t.Errorf("checksum of echo request doesn't verify, got %#04x", sum)
+	}
+
+	// Turn the request into the reply a host would send
+	packet[0] = icmpEchoReply
+	if id, seq, ok := parseIcmpEchoReply(false, packet); !ok || id != 0x1234 || seq != 7 {
+		t.Errorf("parseIcmpEchoReply() = %#x, %d, %v, want 0x1234, 7, true", id, seq, ok)
+	}
+
+	if _, _, ok := parseIcmpEchoReply(false, icmpEcho(false, 1, 1)); ok {
+		t.Errorf("parseIcmpEchoReply() accepted an echo request")
+	}
+
+	if _, _, ok := parseIcmpEchoReply(false, packet[:4]); ok {
+		t.Errorf("parseIcmpEchoReply() accepted a truncated packet")
+	}
+
+	// The kernel fills in ICMPv6 checksums
+	v6 := icmpEcho(true, 1, 1)
+	if v6[0] != icmpv6EchoRequest || v6[2] != 0 || v6[3] != 0 {
+		t.Errorf("icmpEcho(ipv6) = % x, want an echo request without a checksum", v6[:8])
+	}
+}
+
+func TestIcmpStats(t *testing.T) {
+	ms := func(n float64) time.Duration { return time.Duration(n * float64(time.Millisecond)) }
+
+	tests := []struct {
+		name string
+		rtts []time.Duration
+		want rttStats
+	}{
+		{name: "none received", rtts: []time.Duration{0, 0}, want: rttStats{}},
+		{name: "one received", rtts: []time.Duration{ms(5)}, want: rttStats{min: 5, avg: 5, max: 5}},
+		{name: "several", rtts: []time.Duration{ms(10), ms(20), ms(15)}, want: rttStats{min: 10, avg: 15, max: 20, jitter: 7.5}},
+		{name: "lost replies are skipped", rtts: []time.Duration{ms(10), 0, ms(30)}, want: rttStats{min: 10, avg: 20, max: 30, jitter: 20}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := icmpStats(tt.rtts); got != tt.want {
+				t.Errorf("icmpStats() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckIcmpLoopback(t *testing.T) {
+	target := models.Target{Kind: models.TargetKindIcmp, Uri: "127.0.0.1"}
+	target.SetConfig(map[string]interface{}{"count": 3, "interval_ms": 10, "timeout": 1})
+
+	result := checkIcmp(context.Background(), nil, &target)
+	if result.Status == models.StatusUnknown && strings.HasPrefix(result.Message, "cannot open icmp socket") {
+		t.Skip(result.Message)
+	}
+
+	if result.Status != models.StatusUp || result.Data["received"] != 3 {
+		t.Errorf("status = %s, received = %v, want up with every reply (message %q)", result.Status, result.Data["received"], result.Message)
+	}
+}
@@ -0,0 +1,149 @@
+package checks
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/tehlordvortex/updawg/models"
+)
+
+// Kinds which aren't checked by connecting to anything, so can't be checked
+// from remote locations.
+var kindsWithoutLocations = []string{models.TargetKindHeartbeat, models.TargetKindComposite}
+
+// staleLocationIntervals is how many check intervals a location's result is
+// used for before the location is considered to have stopped reporting.
+const staleLocationIntervals = 3
+
+type locationsConfig struct {
+	// Locations are the agent locations the target is checked from instead
+	// of by updawg serve itself.
+	Locations []string `json:"locations"`
+	// Quorum is the number of locations which must fail for the target to
+	// fail, by default a majority of them.
+	Quorum int `json:"quorum"`
+}
+
+// Locations returns the agent locations a target is checked from, if any.
+func Locations(target *models.Target) []string {
+	if slices.Contains(kindsWithoutLocations, target.Kind) {
+		return nil
+	}
+
+	var cfg locationsConfig
+	if err := target.DecodeConfig(&cfg); err != nil {
+		return nil
+	}
+
+	return cfg.Locations
+}
+
+// checkLocations derives the target's status from the latest result of each
+// of its locations. The target is only down or degraded when at least the
+// quorum of locations are, so a single location's network problems don't
+// fail it.
+func checkLocations(ctx context.Context, qe models.QueryExecutor, target *models.Target) models.CheckResult {
+	var cfg locationsConfig
+	if err := target.DecodeConfig(&cfg); err != nil {
+		return unknown("invalid config: %v", err)
+	}
+
+	quorum := cfg.Quorum
+	if quorum == 0 {
+		quorum = len(cfg.Locations)/2 + 1
+	} else if quorum < 0 || quorum > len(cfg.Locations) {
+		return unknown("invalid config: quorum must be between 1 and the number of locations")
+	}
+
+	results, err := models.FindLocationResultsByTargetId(ctx, qe, target.Id())
+	if err != nil {
+		return unknown("failed to load location results: %v", err)
+	}
+
+	latest := make(map[string]models.LocationResult)
+	for _, r := range results {
+		latest[r.Location] = r
+	}
+
+	staleAfter := staleLocationIntervals * checkInterval(target)
+	locations := make(map[string]interface{})
+	var failures []string
+	var upCount, downCount, failingCount int
+	var duration time.Duration
+
+	for _, location := range cfg.Locations {
+		r, ok := latest[location]
+		if !ok {
+			locations[location] = map[string]interface{}{"status": models.StatusUnknown, "message": "no results"}
+			continue
+		}
+
+		status := r.Status
+		message := r.Message
+		if age := time.Since(r.CheckedAt()); age > staleAfter {
+			status = models.StatusUnknown
+			message = fmt.Sprintf("no results for %s", age.Round(time.Second))
+		}
+
+		locations[location] = map[string]interface{}{
+			"status":      status,
+			"message":     message,
+			"agent_id":    r.AgentId,
+			"checked_at":  r.CheckedAt().Unix(),
+			"duration_ms": float64(r.Duration.Microseconds()) / 1000,
+		}
+
+		switch {
+		case status == models.StatusUp:
+			upCount++
+			duration = max(duration, r.Duration)
+		case status.IsFailure():
+			failingCount++
+			if status == models.StatusDown {
+				downCount++
+			}
+
+			failures = append(failures, fmt.Sprintf("%s: %s", location, message))
+		}
+	}
+
+	summary := fmt.Sprintf("%d/%d locations up", upCount, len(cfg.Locations))
+	if len(failures) > 0 {
+		summary += " (" + strings.Join(failures, "; ") + ")"
+	}
+
+	var result models.CheckResult
+	switch {
+	case downCount >= quorum:
+		result = down("%s", summary)
+	case failingCount >= quorum:
+		result = degraded("%s", summary)
+	case upCount == 0:
+		result = unknown("%s", summary)
+	default:
+		result = up("%s", summary)
+	}
+
+	result.Data["locations"] = locations
+	result.Data["quorum"] = quorum
+	result.Duration = duration
+
+	return result
+}
+
+// checkInterval returns roughly how often the target is checked.
+func checkInterval(target *models.Target) time.Duration {
+	interval := time.Duration(target.Period) * time.Second
+
+	if s, err := target.ParseSchedule(); err == nil && s.Cron != nil {
+		next := s.Cron.Next(time.Now().In(s.Location))
+		if after := s.Cron.Next(next); !after.IsZero() {
+			interval = after.Sub(next)
+		}
+	}
+
+	return interval
+}
@@ -0,0 +1,128 @@
+package checks
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/tehlordvortex/updawg/models"
+)
+
+func TestCheckLocations(t *testing.T) {
+	locations := []string{"eu", "us", "ap"}
+
+	tests := []struct {
+		name     string
+		quorum   int
+		statuses map[string]models.Status
+		stale    []string
+		want     models.Status
+	}{
+		{
+			name:     "all up",
+			statuses: map[string]models.Status{"eu": models.StatusUp, "us": models.StatusUp, "ap": models.StatusUp},
+			want:     models.StatusUp,
+		},
+		{
+			name:     "one down is below the default majority",
+			statuses: map[string]models.Status{"eu": models.StatusDown, "us": models.StatusUp, "ap": models.StatusUp},
+			want:     models.StatusUp,
+		},
+		{
+			name:     "majority down",
+			statuses: map[string]models.Status{"eu": models.StatusDown, "us": models.StatusDown, "ap": models.StatusUp},
+			want:     models.StatusDown,
+		},
+		{
+			name:     "majority failing but not down",
+			statuses: map[string]models.Status{"eu": models.StatusDown, "us": models.StatusDegraded, "ap": models.StatusUp},
+			want:     models.StatusDegraded,
+		},
+		{
+			name:     "quorum of one",
+			quorum:   1,
+			statuses: map[string]models.Status{"eu": models.StatusDown, "us": models.StatusUp, "ap": models.StatusUp},
+			want:     models.StatusDown,
+		},
+		{
+			name:     "quorum of every location",
+			quorum:   3,
+			statuses: map[string]models.Status{"eu": models.StatusDown, "us": models.StatusDown, "ap": models.StatusUp},
+			want:     models.StatusUp,
+		},
+		{
+			name:     "missing locations are unknown",
+			statuses: map[string]models.Status{"eu": models.StatusDown},
+			want:     models.StatusUnknown,
+		},
+		{
+			name:     "no results",
+			statuses: map[string]models.Status{},
+			want:     models.StatusUnknown,
+		},
+		{
+			name:     "stale results don't count",
+			statuses: map[string]models.Status{"eu": models.StatusDown, "us": models.StatusDown, "ap": models.StatusUp},
+			stale:    []string{"us"},
+			want:     models.StatusUp,
+		},
+		{
+			name:     "invalid quorum",
+			quorum:   4,
+			statuses: map[string]models.Status{"eu": models.StatusUp},
+			want:     models.StatusUnknown,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDb(t)
+			ctx := context.Background()
+
+			target := models.Target{Kind: models.TargetKindHttp, Uri: "https://example.com", Period: 60}
+			target.SetConfig(map[string]interface{}{"locations": locations, "quorum": tt.quorum})
+			if err := target.Save(ctx, db); err != nil {
+				t.Fatal(err)
+			}
+
+			for location, status := range tt.statuses {
+				result := models.LocationResult{TargetId: target.Id(), Location: location, AgentId: "agent_" + location, Status: status}
+				if err := result.Save(ctx, db); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			staleAt := time.Now().Add(-staleLocationIntervals * 2 * time.Minute).Unix()
+			for _, location := range tt.stale {
+				if _, err := db.ExecContext(ctx, "UPDATE location_results SET checked_at = ? WHERE target_id = ? AND location = ?", staleAt, target.Id(), location); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			result := checkLocations(ctx, db, &target)
+			if result.Status != tt.want {
+				t.Errorf("status = %s, want %s (message %q)", result.Status, tt.want, result.Message)
+			}
+		})
+	}
+}
+
+func TestLocations(t *testing.T) {
+	tests := []struct {
+		kind string
+		want int
+	}{
+		{models.TargetKindHttp, 2},
+		{models.TargetKindHeartbeat, 0},
+		{models.TargetKindComposite, 0},
+	}
+
+	for _, tt := range tests {
+		target := models.Target{Kind: tt.kind}
+		target.SetConfig(map[string]interface{}{"locations": []string{"eu", "us"}})
+
+		if got := Locations(&target); len(got) != tt.want {
+			t.Errorf("Locations() of %s target = %v, want %d locations", tt.kind, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,310 @@
+package checks
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"github.com/tehlordvortex/updawg/config"
+	"github.com/tehlordvortex/updawg/models"
+)
+
+type mailConfig struct {
+	// StartTls upgrades the connection with STARTTLS (STLS for POP3) and
+	// fails the check if the server does not offer it.
+	StartTls bool `json:"starttls"`
+	// Tls connects with implicit TLS, as does using the smtps, imaps or
+	// pop3s scheme in the uri.
+	Tls                bool  `json:"tls"`
+	InsecureSkipVerify bool  `json:"insecure_skip_verify"`
+	Timeout            int64 `json:"timeout"`
+}
+
+type mailProtocol struct {
+	name               string
+	defaultPort        string
+	defaultTlsPort     string
+	startTlsCapability string
+
+	greet        func(tp *textproto.Conn) (string, error)
+	capabilities func(tp *textproto.Conn) ([]string, error)
+	startTls     func(tp *textproto.Conn) error
+	quit         func(tp *textproto.Conn)
+}
+
+// checkMail returns a checker which reads the server greeting, lists its
+// capabilities and optionally upgrades the connection with STARTTLS.
+func checkMail(protocol mailProtocol) Checker {
+	return func(ctx context.Context, qe models.QueryExecutor, target *models.Target) models.CheckResult {
+		var cfg mailConfig
+		if err := target.DecodeConfig(&cfg); err != nil {
+			return unknown("invalid config: %v", err)
+		}
+
+		if cfg.Timeout <= 0 {
+			cfg.Timeout = config.DefaultTimeout
+		}
+
+		scheme, host, port, err := parseAddress(target.Uri, "")
+		if err != nil {
+			return unknown("invalid uri: %v", err)
+		}
+
+		implicitTls := cfg.Tls || scheme == protocol.name+"s"
+		if port == "" && implicitTls {
+			port = protocol.defaultTlsPort
+		} else if port == "" {
+			port = protocol.defaultPort
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, time.Duration(cfg.Timeout)*time.Second)
+		defer cancel()
+
+		conn, err := dialContext(ctx, "tcp", net.JoinHostPort(host, port))
+		if err != nil {
+			return down("%v", err)
+		}
+		defer conn.Close()
+
+		if deadline, ok := ctx.Deadline(); ok {
+			conn.SetDeadline(deadline)
+		}
+
+		tlsConfig := &tls.Config{ServerName: host, InsecureSkipVerify: cfg.InsecureSkipVerify}
+
+		var tlsState *tls.ConnectionState
+		if implicitTls {
+			tlsConn := tls.Client(conn, tlsConfig)
+			if err := tlsConn.HandshakeContext(ctx); err != nil {
+				return down("tls handshake failed: %v", err)
+			}
+
+			state := tlsConn.ConnectionState()
+			tlsState = &state
+			conn = tlsConn
+		}
+
+		tp := textproto.NewConn(conn)
+
+		banner, err := protocol.greet(tp)
+		if err != nil {
+			return down("greeting failed: %v", err)
+		}
+
+		capabilities, err := protocol.capabilities(tp)
+		if err != nil {
+			return down("capabilities failed: %v", err)
+		}
+
+		if cfg.StartTls && tlsState == nil {
+			if !hasCapability(capabilities, protocol.startTlsCapability) {
+				return down("%s not supported", protocol.startTlsCapability)
+			}
+
+			if err := protocol.startTls(tp); err != nil {
+				return down("%s failed: %v", protocol.startTlsCapability, err)
+			}
+
+			tlsConn := tls.Client(conn, tlsConfig)
+			if err := tlsConn.HandshakeContext(ctx); err != nil {
+				return down("tls handshake failed: %v", err)
+			}
+
+			state := tlsConn.ConnectionState()
+			tlsState = &state
+			tp = textproto.NewConn(tlsConn)
+		}
+
+		protocol.quit(tp)
+
+		result := up("%s", banner)
+		result.Data["banner"] = banner
+		result.Data["capabilities"] = capabilities
+		if tlsState != nil {
+			result.Data["tls"] = tlsData(*tlsState)
+		}
+
+		return result
+	}
+}
+
+func hasCapability(capabilities []string, capability string) bool {
+	for _, c := range capabilities {
+		name, _, _ := strings.Cut(c, " ")
+		if strings.EqualFold(name, capability) {
+			return true
+		}
+	}
+
+	return false
+}
+
+var smtpProtocol = mailProtocol{
+	name:               "smtp",
+	defaultPort:        "25",
+	defaultTlsPort:     "465",
+	startTlsCapability: "STARTTLS",
+
+	greet: func(tp *textproto.Conn) (string, error) {
+		_, msg, err := tp.ReadResponse(220)
+		if err != nil {
+			return "", err
+		}
+
+		banner, _, _ := strings.Cut(msg, "\n")
+		return banner, nil
+	},
+	capabilities: func(tp *textproto.Conn) ([]string, error) {
+		msg, err := smtpCommand(tp, 250, "EHLO updawg")
+		if err != nil {
+			return nil, err
+		}
+
+		// The first line is the server's greeting
+		lines := strings.Split(msg, "\n")
+		return lines[1:], nil
+	},
+	startTls: func(tp *textproto.Conn) error {
+		_, err := smtpCommand(tp, 220, "STARTTLS")
+		return err
+	},
+	quit: func(tp *textproto.Conn) {
+		_, _ = smtpCommand(tp, 221, "QUIT")
+	},
+}
+
+func smtpCommand(tp *textproto.Conn, expectCode int, format string, args ...interface{}) (string, error) {
+	id, err := tp.Cmd(format, args...)
+	if err != nil {
+		return "", err
+	}
+
+	tp.StartResponse(id)
+	defer tp.EndResponse(id)
+
+	_, msg, err := tp.ReadResponse(expectCode)
+	return msg, err
+}
+
+var imapProtocol = mailProtocol{
+	name:               "imap",
+	defaultPort:        "143",
+	defaultTlsPort:     "993",
+	startTlsCapability: "STARTTLS",
+
+	greet: func(tp *textproto.Conn) (string, error) {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return "", err
+		}
+
+		if !strings.HasPrefix(line, "* OK") && !strings.HasPrefix(line, "* PREAUTH") {
+			return "", fmt.Errorf("unexpected greeting: %s", line)
+		}
+
+		return strings.TrimSpace(strings.TrimPrefix(line, "*")), nil
+	},
+	capabilities: func(tp *textproto.Conn) ([]string, error) {
+		untagged, err := imapCommand(tp, "a1", "CAPABILITY")
+		if err != nil {
+			return nil, err
+		}
+
+		for _, line := range untagged {
+			if rest, found := strings.CutPrefix(line, "CAPABILITY "); found {
+				return strings.Fields(rest), nil
+			}
+		}
+
+		return nil, fmt.Errorf("no capabilities returned")
+	},
+	startTls: func(tp *textproto.Conn) error {
+		_, err := imapCommand(tp, "a2", "STARTTLS")
+		return err
+	},
+	quit: func(tp *textproto.Conn) {
+		_, _ = imapCommand(tp, "a3", "LOGOUT")
+	},
+}
+
+// imapCommand sends a tagged command and returns its untagged responses
+// without the leading "* ".
+func imapCommand(tp *textproto.Conn, tag, command string) ([]string, error) {
+	if err := tp.PrintfLine("%s %s", tag, command); err != nil {
+		return nil, err
+	}
+
+	var untagged []string
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return nil, err
+		}
+
+		if rest, found := strings.CutPrefix(line, "* "); found {
+			untagged = append(untagged, rest)
+			continue
+		}
+
+		if rest, found := strings.CutPrefix(line, tag+" "); found {
+			if !strings.HasPrefix(rest, "OK") {
+				return nil, fmt.Errorf("%s: %s", command, rest)
+			}
+
+			return untagged, nil
+		}
+	}
+}
+
+var pop3Protocol = mailProtocol{
+	name:               "pop3",
+	defaultPort:        "110",
+	defaultTlsPort:     "995",
+	startTlsCapability: "STLS",
+
+	greet: func(tp *textproto.Conn) (string, error) {
+		return pop3Response(tp)
+	},
+	capabilities: func(tp *textproto.Conn) ([]string, error) {
+		if err := tp.PrintfLine("CAPA"); err != nil {
+			return nil, err
+		}
+
+		if _, err := pop3Response(tp); err != nil {
+			return nil, err
+		}
+
+		return tp.ReadDotLines()
+	},
+	startTls: func(tp *textproto.Conn) error {
+		if err := tp.PrintfLine("STLS"); err != nil {
+			return err
+		}
+
+		_, err := pop3Response(tp)
+		return err
+	},
+	quit: func(tp *textproto.Conn) {
+		if err := tp.PrintfLine("QUIT"); err == nil {
+			_, _ = pop3Response(tp)
+		}
+	},
+}
+
+func pop3Response(tp *textproto.Conn) (string, error) {
+	line, err := tp.ReadLine()
+	if err != nil {
+		return "", err
+	}
+
+	rest, ok := strings.CutPrefix(line, "+OK")
+	if !ok {
+		return "", fmt.Errorf("unexpected response: %s", line)
+	}
+
+	return strings.TrimSpace(rest), nil
+}
@@ -0,0 +1,160 @@
+package checks
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tehlordvortex/updawg/models"
+)
+
+// fakeMailServer sends greeting then answers each command with its response,
+// closing the connection on any other command. Answering upgrade switches
+// the connection to TLS, as does tls from the start.
+type fakeMailServer struct {
+	tls       bool
+	greeting  string
+	responses map[string]string
+	upgrade   string
+}
+
+// serve accepts a single connection, returning the address to connect to.
+func (s fakeMailServer) serve(t *testing.T) string {
+	t.Helper()
+
+	cert, _ := newTestCert(t, time.Now().Add(24*time.Hour))
+	tlsConfig := &tls.Config{Certificates: []tls.Certificate{cert}}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer func() { conn.Close() }()
+
+		if s.tls {
+			conn = tls.Server(conn, tlsConfig)
+		}
+
+		conn.Write([]byte(s.greeting))
+		r := bufio.NewReader(conn)
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+
+			command := strings.TrimRight(line, "\r\n")
+			response, ok := s.responses[command]
+			if !ok {
+				return
+			}
+
+			conn.Write([]byte(response))
+			if command == s.upgrade {
+				conn = tls.Server(conn, tlsConfig)
+				r = bufio.NewReader(conn)
+			}
+		}
+	}()
+
+	return ln.Addr().String()
+}
+
+func TestCheckMail(t *testing.T) {
+	smtp := func(capabilities string) fakeMailServer {
+		return fakeMailServer{
+			greeting: "220 mail.test ESMTP ready\r\n",
+			responses: map[string]string{
+				"EHLO updawg": "250-mail.test\r\n" + capabilities,
+				"STARTTLS":    "220 go ahead\r\n",
+				"QUIT":        "221 bye\r\n",
+			},
+			upgrade: "STARTTLS",
+		}
+	}
+
+	imap := fakeMailServer{
+		greeting: "* OK IMAP ready\r\n",
+		responses: map[string]string{
+			"a1 CAPABILITY": "* CAPABILITY IMAP4rev1 STARTTLS\r\na1 OK done\r\n",
+			"a2 STARTTLS":   "a2 OK begin\r\n",
+			"a3 LOGOUT":     "* BYE\r\na3 OK done\r\n",
+		},
+		upgrade: "a2 STARTTLS",
+	}
+
+	pop3 := fakeMailServer{
+		greeting: "+OK POP3 ready\r\n",
+		responses: map[string]string{
+			"CAPA": "+OK\r\nUSER\r\nSTLS\r\n.\r\n",
+			"STLS": "+OK begin\r\n",
+			"QUIT": "+OK bye\r\n",
+		},
+		upgrade: "STLS",
+	}
+	pop3s := pop3
+	pop3s.tls = true
+	pop3Busy := pop3
+	pop3Busy.greeting = "-ERR busy\r\n"
+
+	imapBye := imap
+	imapBye.greeting = "* BYE shutting down\r\n"
+
+	starttls := map[string]interface{}{"starttls": true, "insecure_skip_verify": true}
+
+	tests := []struct {
+		name       string
+		protocol   mailProtocol
+		server     fakeMailServer
+		scheme     string
+		config     map[string]interface{}
+		want       models.Status
+		wantBanner string
+		wantTls    bool
+	}{
+		{name: "smtp", protocol: smtpProtocol, server: smtp("250 STARTTLS\r\n"), want: models.StatusUp, wantBanner: "mail.test ESMTP ready"},
+		{name: "smtp starttls", protocol: smtpProtocol, server: smtp("250 STARTTLS\r\n"), config: starttls, want: models.StatusUp, wantBanner: "mail.test ESMTP ready", wantTls: true},
+		{name: "smtp without starttls", protocol: smtpProtocol, server: smtp("250 PIPELINING\r\n"), config: starttls, want: models.StatusDown},
+		{name: "smtp rejected", protocol: smtpProtocol, server: fakeMailServer{greeting: "554 go away\r\n"}, want: models.StatusDown},
+		{name: "imap", protocol: imapProtocol, server: imap, want: models.StatusUp, wantBanner: "OK IMAP ready"},
+		{name: "imap starttls", protocol: imapProtocol, server: imap, config: starttls, want: models.StatusUp, wantBanner: "OK IMAP ready", wantTls: true},
+		{name: "imap unexpected greeting", protocol: imapProtocol, server: imapBye, want: models.StatusDown},
+		{name: "pop3", protocol: pop3Protocol, server: pop3, want: models.StatusUp, wantBanner: "POP3 ready"},
+		{name: "pop3 stls", protocol: pop3Protocol, server: pop3, config: starttls, want: models.StatusUp, wantBanner: "POP3 ready", wantTls: true},
+		{name: "pop3 implicit tls", protocol: pop3Protocol, server: pop3s, scheme: "pop3s://", config: map[string]interface{}{"insecure_skip_verify": true}, want: models.StatusUp, wantBanner: "POP3 ready", wantTls: true},
+		{name: "pop3 untrusted certificate", protocol: pop3Protocol, server: pop3s, scheme: "pop3s://", want: models.StatusDown},
+		{name: "pop3 busy", protocol: pop3Protocol, server: pop3Busy, want: models.StatusDown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := models.Target{Uri: tt.scheme + tt.server.serve(t)}
+			target.SetConfig(map[string]interface{}{"timeout": 5})
+			target.SetConfig(tt.config)
+
+			result := checkMail(tt.protocol)(context.Background(), nil, &target)
+			if result.Status != tt.want {
+				t.Fatalf("status = %s, want %s (message %q)", result.Status, tt.want, result.Message)
+			}
+
+			if banner, _ := result.Data["banner"].(string); banner != tt.wantBanner {
+				t.Errorf("banner = %q, want %q", banner, tt.wantBanner)
+			}
+
+			if _, ok := result.Data["tls"]; ok != tt.wantTls {
+				t.Errorf("tls data present = %v, want %v", ok, tt.wantTls)
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package checks
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"slices"
+	"time"
+
+	_ "modernc.org/sqlite"
+
+	"github.com/tehlordvortex/updawg/config"
+	"github.com/tehlordvortex/updawg/models"
+)
+
+const (
+	DefaultSqlDriver = "sqlite"
+	DefaultSqlQuery  = "SELECT 1"
+)
+
+type sqlConfig struct {
+	// Dsn is the data source name given to the driver. It's kept in the
+	// config rather than the uri as it usually contains a password.
+	Dsn    string `json:"dsn"`
+	Driver string `json:"driver"`
+	Query  string `json:"query"`
+	// Expect, if set, is compared against the first column of the first row.
+	Expect *string `json:"expect"`
+	// ExpectRows, if set, is compared against the number of rows returned.
+	ExpectRows *int64 `json:"expect_rows"`
+	// Timeout is the number of seconds connecting and querying may take.
+	Timeout int64 `json:"timeout"`
+}
+
+// checkSql connects to the configured DSN with a registered database/sql
+// driver and runs the configured query.
+func checkSql(ctx context.Context, qe models.QueryExecutor, target *models.Target) models.CheckResult {
+	var cfg sqlConfig
+	if err := target.DecodeConfig(&cfg); err != nil {
+		return unknown("invalid config: %v", err)
+	}
+
+	if cfg.Dsn == "" {
+		return unknown("missing dsn")
+	}
+
+	if cfg.Driver == "" {
+		cfg.Driver = DefaultSqlDriver
+	}
+
+	if cfg.Query == "" {
+		cfg.Query = DefaultSqlQuery
+	}
+
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = config.DefaultTimeout
+	}
+
+	if !slices.Contains(sql.Drivers(), cfg.Driver) {
+		return unknown("unknown driver: %s", cfg.Driver)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(cfg.Timeout)*time.Second)
+	defer cancel()
+
+	db, err := sql.Open(cfg.Driver, cfg.Dsn)
+	if err != nil {
+		return down("%v", err)
+	}
+	defer db.Close()
+
+	if err := db.PingContext(ctx); err != nil {
+		return down("connect failed: %v", err)
+	}
+
+	rows, err := db.QueryContext(ctx, cfg.Query)
+	if err != nil {
+		return down("query failed: %v", err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return down("query failed: %v", err)
+	}
+
+	var scalar interface{}
+	var rowCount int64
+
+	for rows.Next() {
+		if rowCount == 0 && len(columns) > 0 {
+			cols := make([]interface{}, len(columns))
+			cols[0] = &scalar
+			for i := 1; i < len(cols); i++ {
+				cols[i] = new(interface{})
+			}
+
+			if err := rows.Scan(cols...); err != nil {
+				return down("scan failed: %v", err)
+			}
+		}
+
+		rowCount++
+	}
+
+	if err := rows.Err(); err != nil {
+		return down("query failed: %v", err)
+	}
+
+	scalarString := formatScalar(scalar)
+
+	var result models.CheckResult
+	if cfg.Expect != nil && scalarString != *cfg.Expect {
+		result = down("expected %q, got %q", *cfg.Expect, scalarString)
+	} else if cfg.ExpectRows != nil && rowCount != *cfg.ExpectRows {
+		result = down("expected %d rows, got %d", *cfg.ExpectRows, rowCount)
+	} else {
+		result = up("%d rows", rowCount)
+	}
+
+	result.Data["driver"] = cfg.Driver
+	result.Data["rows"] = rowCount
+	if rowCount > 0 {
+		result.Data["scalar"] = scalarString
+	}
+
+	return result
+}
+
+func formatScalar(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case []byte:
+		return string(v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,51 @@
+package checks
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	"github.com/tehlordvortex/updawg/models"
+)
+
+func TestCheckSql(t *testing.T) {
+	dsn := filepath.Join(t.TempDir(), "checked.db")
+
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if _, err := db.Exec("CREATE TABLE jobs (name text); INSERT INTO jobs VALUES ('backup'), ('report')"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		config map[string]interface{}
+		want   models.Status
+	}{
+		{name: "default query", config: map[string]interface{}{"dsn": dsn}, want: models.StatusUp},
+		{name: "expected value", config: map[string]interface{}{"dsn": dsn, "query": "SELECT count(*) FROM jobs", "expect": "2"}, want: models.StatusUp},
+		{name: "unexpected value", config: map[string]interface{}{"dsn": dsn, "query": "SELECT count(*) FROM jobs", "expect": "3"}, want: models.StatusDown},
+		{name: "expected rows", config: map[string]interface{}{"dsn": dsn, "query": "SELECT name FROM jobs", "expect_rows": 2}, want: models.StatusUp},
+		{name: "unexpected rows", config: map[string]interface{}{"dsn": dsn, "query": "SELECT name FROM jobs", "expect_rows": 0}, want: models.StatusDown},
+		{name: "query fails", config: map[string]interface{}{"dsn": dsn, "query": "SELECT * FROM missing"}, want: models.StatusDown},
+		{name: "missing dsn", want: models.StatusUnknown},
+		{name: "unknown driver", config: map[string]interface{}{"dsn": dsn, "driver": "nosuchdb"}, want: models.StatusUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := models.Target{Kind: models.TargetKindSql}
+			target.SetConfig(tt.config)
+
+			result := checkSql(context.Background(), nil, &target)
+			if result.Status != tt.want {
+				t.Errorf("status = %s, want %s (message %q)", result.Status, tt.want, result.Message)
+			}
+		})
+	}
+}
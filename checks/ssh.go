@@ -0,0 +1,157 @@
+package checks
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tehlordvortex/updawg/config"
+	"github.com/tehlordvortex/updawg/models"
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	sshClientVersion = "SSH-2.0-updawg"
+	sshUser          = "updawg"
+	// sshMaxBannerSize bounds how much of the connection is kept to find the
+	// server's identification string.
+	sshMaxBannerSize = 16 * 1024
+)
+
+var errSshHostKeyChanged = errors.New("host key changed")
+
+type sshConfig struct {
+	// Fingerprint pins the host key, in the SHA256:base64 format printed by
+	// ssh-keygen -l.
+	Fingerprint string `json:"fingerprint"`
+	// HostKeyAlgorithm restricts the host key type requested from the
+	// server, for servers with several host keys.
+	HostKeyAlgorithm string `json:"host_key_algorithm"`
+	Timeout          int64  `json:"timeout"`
+}
+
+// checkSsh connects to an SSH server and completes key exchange, which
+// verifies the server's host key signature, then compares the host key
+// against the pinned fingerprint. The server is up once it asks the client
+// to authenticate, which is never attempted.
+func checkSsh(ctx context.Context, qe models.QueryExecutor, target *models.Target) models.CheckResult {
+	var cfg sshConfig
+	if err := target.DecodeConfig(&cfg); err != nil {
+		return unknown("invalid config: %v", err)
+	}
+
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = config.DefaultTimeout
+	}
+
+	_, host, port, err := parseAddress(target.Uri, "22")
+	if err != nil {
+		return unknown("invalid uri: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(cfg.Timeout)*time.Second)
+	defer cancel()
+
+	addr := net.JoinHostPort(host, port)
+	netConn, err := dialContext(ctx, "tcp", addr)
+	if err != nil {
+		return down("%v", err)
+	}
+	defer netConn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		netConn.SetDeadline(deadline)
+	}
+
+	var hostKey ssh.PublicKey
+	var fingerprint string
+	clientConfig := &ssh.ClientConfig{
+		User:          sshUser,
+		ClientVersion: sshClientVersion,
+		HostKeyCallback: func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+			hostKey, fingerprint = key, ssh.FingerprintSHA256(key)
+			if cfg.Fingerprint != "" && cfg.Fingerprint != fingerprint {
+				return errSshHostKeyChanged
+			}
+
+			return nil
+		},
+	}
+	if cfg.HostKeyAlgorithm != "" {
+		clientConfig.HostKeyAlgorithms = []string{cfg.HostKeyAlgorithm}
+	}
+
+	conn := &sshBannerConn{Conn: netConn}
+	sshConn, _, _, err := ssh.NewClientConn(conn, addr, clientConfig)
+	if sshConn != nil {
+		sshConn.Close()
+	}
+
+	banner := conn.banner()
+
+	var result models.CheckResult
+	switch {
+	case hostKey == nil:
+		// Key exchange didn't complete, so the host key was never verified
+		result = down("%v", err)
+	case errors.Is(err, errSshHostKeyChanged):
+		result = down("host key changed: expected %s, got %s", cfg.Fingerprint, fingerprint)
+		result.Data["host_key_changed"] = true
+	default:
+		// Any other error is the server refusing to let us in without
+		// credentials, which it only does after proving its identity.
+		result = up("%s", banner)
+	}
+
+	if banner != "" {
+		result.Data["banner"] = banner
+	}
+
+	if hostKey != nil {
+		result.Data["host_key_algorithm"] = hostKey.Type()
+		result.Data["fingerprint"] = fingerprint
+	}
+
+	return result
+}
+
+// sshBannerConn keeps the start of what the server sends so its
+// identification string can be reported, which the ssh package only exposes
+// on connections which authenticate. The ssh package keeps reading from it
+// in the background after a failed handshake, so received is guarded by mu.
+type sshBannerConn struct {
+	net.Conn
+	mu       sync.Mutex
+	received bytes.Buffer
+}
+
+func (c *sshBannerConn) Read(b []byte) (int, error) {
+	n, err := c.Conn.Read(b)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if remaining := sshMaxBannerSize - c.received.Len(); remaining > 0 {
+		c.received.Write(b[:min(n, remaining)])
+	}
+
+	return n, err
+}
+
+// banner returns the server's identification string, skipping any lines sent
+// before it (RFC 4253 section 4.2).
+func (c *sshBannerConn) banner() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, line := range strings.Split(c.received.String(), "\n") {
+		if strings.HasPrefix(line, "SSH-") {
+			return strings.TrimRight(line, "\r")
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,143 @@
+package checks
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"net"
+	"testing"
+
+	"github.com/tehlordvortex/updawg/models"
+	"golang.org/x/crypto/ssh"
+)
+
+// serveSsh runs an SSH server with an ed25519 host key which refuses every
+// client, returning its address and host key.
+func serveSsh(t *testing.T) (string, ssh.PublicKey) {
+	t.Helper()
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	signer, err := ssh.NewSignerFromKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	config := &ssh.ServerConfig{
+		ServerVersion: "SSH-2.0-OpenSSH_9.6 updawg-test",
+		PasswordCallback: func(ssh.ConnMetadata, []byte) (*ssh.Permissions, error) {
+			return nil, ssh.ErrNoAuth
+		},
+	}
+	config.AddHostKey(signer)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+
+			go func() {
+				defer conn.Close()
+				_, _, _, _ = ssh.NewServerConn(conn, config)
+			}()
+		}
+	}()
+
+	return ln.Addr().String(), signer.PublicKey()
+}
+
+func TestCheckSsh(t *testing.T) {
+	addr, hostKey := serveSsh(t)
+	fingerprint := ssh.FingerprintSHA256(hostKey)
+
+	// Find a port nothing is listening on
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed := ln.Addr().String()
+	ln.Close()
+
+	tests := []struct {
+		name            string
+		uri             string
+		config          map[string]interface{}
+		want            models.Status
+		wantFingerprint string
+		wantChanged     bool
+	}{
+		{name: "not pinned", uri: addr, want: models.StatusUp, wantFingerprint: fingerprint},
+		{name: "known host key", uri: addr, config: map[string]interface{}{"fingerprint": fingerprint}, want: models.StatusUp, wantFingerprint: fingerprint},
+		{
+			name:            "unknown host key",
+			uri:             addr,
+			config:          map[string]interface{}{"fingerprint": "SHA256:47DEQpj8HBSa+/TImW+5JCeuQeRkm5NMpJWZG3hSuFU"},
+			want:            models.StatusDown,
+			wantFingerprint: fingerprint,
+			wantChanged:     true,
+		},
+		{name: "host key algorithm not offered", uri: addr, config: map[string]interface{}{"host_key_algorithm": ssh.KeyAlgoRSASHA256}, want: models.StatusDown},
+		{name: "refused", uri: closed, want: models.StatusDown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := models.Target{Kind: models.TargetKindSsh, Uri: tt.uri}
+			target.SetConfig(tt.config)
+
+			result := checkSsh(context.Background(), nil, &target)
+			if result.Status != tt.want {
+				t.Fatalf("status = %s, want %s (message %q)", result.Status, tt.want, result.Message)
+			}
+
+			if got, _ := result.Data["fingerprint"].(string); got != tt.wantFingerprint {
+				t.Errorf("fingerprint = %q, want %q", got, tt.wantFingerprint)
+			}
+
+			if changed, _ := result.Data["host_key_changed"].(bool); changed != tt.wantChanged {
+				t.Errorf("host_key_changed = %v, want %v", changed, tt.wantChanged)
+			}
+
+			if tt.wantFingerprint != "" {
+				if banner := result.Data["banner"]; banner != "SSH-2.0-OpenSSH_9.6 updawg-test" {
+					t.Errorf("banner = %q, want the server's identification", banner)
+				}
+
+				if algorithm := result.Data["host_key_algorithm"]; algorithm != ssh.KeyAlgoED25519 {
+					t.Errorf("host_key_algorithm = %q, want %s", algorithm, ssh.KeyAlgoED25519)
+				}
+			}
+		})
+	}
+}
+
+func TestSshBanner(t *testing.T) {
+	tests := []struct {
+		received string
+		want     string
+	}{
+		{"SSH-2.0-OpenSSH_9.6\r\n", "SSH-2.0-OpenSSH_9.6"},
+		{"Welcome\r\nauthorised users only\r\nSSH-2.0-dropbear\r\n", "SSH-2.0-dropbear"},
+		{"HTTP/1.1 400 Bad Request\r\n", ""},
+	}
+
+	for _, tt := range tests {
+		conn := &sshBannerConn{}
+		conn.received.WriteString(tt.received)
+
+		if got := conn.banner(); got != tt.want {
+			t.Errorf("banner() of %q = %q, want %q", tt.received, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,275 @@
+package checks
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/tehlordvortex/updawg/config"
+	"github.com/tehlordvortex/updawg/models"
+)
+
+// tlsData describes a TLS connection and its leaf certificate. It is stored
+// under the "tls" key of check results for every kind that speaks TLS.
+func tlsData(state tls.ConnectionState) map[string]interface{} {
+	data := map[string]interface{}{
+		"version": tls.VersionName(state.Version),
+		"cipher":  tls.CipherSuiteName(state.CipherSuite),
+	}
+
+	if len(state.PeerCertificates) == 0 {
+		return data
+	}
+
+	cert := state.PeerCertificates[0]
+	data["subject"] = cert.Subject.String()
+	data["issuer"] = cert.Issuer.String()
+	data["dns_names"] = cert.DNSNames
+	data["not_before"] = cert.NotBefore.Unix()
+	data["not_after"] = cert.NotAfter.Unix()
+	data["days_remaining"] = int64(time.Until(cert.NotAfter).Hours() / 24)
+
+	return data
+}
+
+var tlsWeakVersions = []uint16{tls.VersionTLS10, tls.VersionTLS11}
+
+type tlsConfig struct {
+	// ServerName overrides the host used for SNI and hostname verification.
+	ServerName string `json:"server_name"`
+	// ExpectedIssuers are matched against the subject and SHA-256
+	// fingerprint of every certificate above the leaf. The certificate is
+	// flagged unless one of them matches.
+	ExpectedIssuers []string `json:"expected_issuers"`
+	// ExpiryWarningDays is how close to expiry the certificate must be to be
+	// flagged.
+	ExpiryWarningDays int64 `json:"expiry_warning_days"`
+	// CaFile is a PEM file of roots to trust instead of the system roots,
+	// for endpoints using an internal CA.
+	CaFile  string `json:"ca_file"`
+	Timeout int64  `json:"timeout"`
+}
+
+// tlsFinding is a problem found while auditing a TLS endpoint. Its status is
+// the worst the target can be while the finding is present.
+type tlsFinding struct {
+	code    string
+	status  models.Status
+	message string
+}
+
+// checkTls audits a TLS endpoint's configuration: the negotiated protocol
+// and cipher, whether TLS 1.0, 1.1 or weak ciphers are still accepted, and
+// whether the certificate matches the host, chains to a trusted root through
+// the intermediates sent and was issued by an expected CA.
+func checkTls(ctx context.Context, qe models.QueryExecutor, target *models.Target) models.CheckResult {
+	cfg := tlsConfig{ExpiryWarningDays: 14}
+	if err := target.DecodeConfig(&cfg); err != nil {
+		return unknown("invalid config: %v", err)
+	}
+
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = config.DefaultTimeout
+	}
+
+	_, host, port, err := parseAddress(target.Uri, "443")
+	if err != nil {
+		return unknown("invalid uri: %v", err)
+	}
+
+	var roots *x509.CertPool
+	if cfg.CaFile != "" {
+		pem, err := os.ReadFile(cfg.CaFile)
+		if err != nil {
+			return unknown("invalid config: %v", err)
+		}
+
+		roots = x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(pem) {
+			return unknown("invalid config: no certificates in %s", cfg.CaFile)
+		}
+	}
+
+	serverName := host
+	if cfg.ServerName != "" {
+		serverName = cfg.ServerName
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(cfg.Timeout)*time.Second)
+	defer cancel()
+
+	addr := net.JoinHostPort(host, port)
+
+	state, err := tlsHandshake(ctx, addr, &tls.Config{ServerName: serverName, MinVersion: tls.VersionTLS10})
+	if err != nil {
+		return down("%v", err)
+	}
+
+	if len(state.PeerCertificates) == 0 {
+		return down("no certificate presented")
+	}
+
+	var findings []tlsFinding
+	addFinding := func(code string, status models.Status, format string, args ...interface{}) {
+		findings = append(findings, tlsFinding{code: code, status: status, message: fmt.Sprintf(format, args...)})
+	}
+
+	// Probe for legacy protocols and ciphers the server still accepts even
+	// though better ones were negotiated
+	weakVersions := []string{}
+	for _, version := range tlsWeakVersions {
+		if _, err := tlsHandshake(ctx, addr, &tls.Config{ServerName: serverName, MinVersion: version, MaxVersion: version}); err == nil {
+			weakVersions = append(weakVersions, tls.VersionName(version))
+		}
+	}
+
+	if len(weakVersions) > 0 {
+		addFinding("weak_protocol", models.StatusDegraded, "accepts %s", strings.Join(weakVersions, ", "))
+	}
+
+	weakCiphers := make([]uint16, 0)
+	for _, suite := range tls.InsecureCipherSuites() {
+		weakCiphers = append(weakCiphers, suite.ID)
+	}
+
+	weakState, err := tlsHandshake(ctx, addr, &tls.Config{ServerName: serverName, MinVersion: tls.VersionTLS10, MaxVersion: tls.VersionTLS12, CipherSuites: weakCiphers})
+	if err == nil {
+		addFinding("weak_cipher", models.StatusDegraded, "accepts %s", tls.CipherSuiteName(weakState.CipherSuite))
+	}
+
+	leaf := state.PeerCertificates[0]
+	if err := leaf.VerifyHostname(serverName); err != nil {
+		addFinding("hostname_mismatch", models.StatusDown, "%v", err)
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range state.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	chains, err := leaf.Verify(x509.VerifyOptions{Intermediates: intermediates, Roots: roots})
+	var unknownAuthority x509.UnknownAuthorityError
+	switch {
+	case err == nil:
+	case errors.As(err, &unknownAuthority):
+		// Servers may leave out the root but should send every intermediate,
+		// so a chain ending at the leaf is missing an intermediate
+		last := state.PeerCertificates[len(state.PeerCertificates)-1]
+		if last.IsCA || bytes.Equal(last.RawIssuer, last.RawSubject) {
+			addFinding("untrusted_root", models.StatusDown, "chain does not lead to a trusted root: %s", last.Issuer)
+		} else {
+			addFinding("incomplete_chain", models.StatusDown, "issuer of %s not sent by server", last.Subject)
+		}
+	default:
+		addFinding("invalid_certificate", models.StatusDown, "%v", err)
+	}
+
+	// Prefer the verified chain, which includes the root, over what the
+	// server sent
+	issuers := state.PeerCertificates[1:]
+	if len(chains) > 0 {
+		issuers = chains[0][1:]
+	}
+
+	if len(cfg.ExpectedIssuers) > 0 && !tlsIssuedByExpected(leaf, issuers, cfg.ExpectedIssuers) {
+		addFinding("unexpected_ca", models.StatusDown, "issued by unexpected CA %s", leaf.Issuer)
+	}
+
+	daysRemaining := int64(time.Until(leaf.NotAfter).Hours() / 24)
+	if time.Now().After(leaf.NotAfter) {
+		addFinding("expired", models.StatusDown, "certificate expired %s", leaf.NotAfter.Format(time.RFC3339))
+	} else if daysRemaining < cfg.ExpiryWarningDays {
+		addFinding("expiring", models.StatusDegraded, "certificate expires in %d days", daysRemaining)
+	}
+
+	summary := fmt.Sprintf("%s %s", tls.VersionName(state.Version), tls.CipherSuiteName(state.CipherSuite))
+
+	status := models.StatusUp
+	var messages []string
+	findingsData := make([]map[string]interface{}, 0, len(findings))
+	for _, finding := range findings {
+		if finding.status == models.StatusDown || status == models.StatusUp {
+			status = finding.status
+		}
+
+		messages = append(messages, finding.message)
+		findingsData = append(findingsData, map[string]interface{}{
+			"code":    finding.code,
+			"status":  finding.status,
+			"message": finding.message,
+		})
+	}
+
+	if len(messages) > 0 {
+		summary += ": " + strings.Join(messages, "; ")
+	}
+
+	result := newResult(status, "%s", summary)
+
+	chainData := make([]string, 0, len(state.PeerCertificates))
+	for _, cert := range state.PeerCertificates {
+		chainData = append(chainData, cert.Subject.String())
+	}
+
+	data := tlsData(state)
+	data["chain"] = chainData
+	data["weak_versions"] = weakVersions
+
+	result.Data["tls"] = data
+	result.Data["findings"] = findingsData
+
+	return result
+}
+
+// tlsHandshake connects to addr and completes a handshake without verifying
+// the certificate, which is audited separately.
+func tlsHandshake(ctx context.Context, addr string, tlsConfig *tls.Config) (tls.ConnectionState, error) {
+	conn, err := dialContext(ctx, "tcp", addr)
+	if err != nil {
+		return tls.ConnectionState{}, err
+	}
+	defer conn.Close()
+
+	tlsConfig.InsecureSkipVerify = true
+	tlsConn := tls.Client(conn, tlsConfig)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		return tls.ConnectionState{}, fmt.Errorf("tls handshake failed: %v", err)
+	}
+
+	return tlsConn.ConnectionState(), nil
+}
+
+func tlsIssuedByExpected(leaf *x509.Certificate, issuers []*x509.Certificate, expected []string) bool {
+	subjects := []string{leaf.Issuer.String()}
+	var fingerprints []string
+	for _, cert := range issuers {
+		sum := sha256.Sum256(cert.Raw)
+		subjects = append(subjects, cert.Subject.String())
+		fingerprints = append(fingerprints, hex.EncodeToString(sum[:]))
+	}
+
+	for _, want := range expected {
+		normalized := strings.ToLower(strings.ReplaceAll(want, ":", ""))
+		if slices.Contains(fingerprints, normalized) {
+			return true
+		}
+
+		for _, subject := range subjects {
+			if strings.Contains(strings.ToLower(subject), strings.ToLower(want)) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
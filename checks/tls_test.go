@@ -0,0 +1,77 @@
+package checks
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/tehlordvortex/updawg/models"
+)
+
+func TestCheckTls(t *testing.T) {
+	day := 24 * time.Hour
+
+	tests := []struct {
+		name      string
+		expiresIn time.Duration
+		// trusted trusts the server's certificate through ca_file
+		trusted bool
+		// minVersion is the oldest protocol the server accepts
+		minVersion   uint16
+		config       map[string]interface{}
+		want         models.Status
+		wantFindings []string
+	}{
+		{name: "valid", expiresIn: 365 * day, trusted: true, want: models.StatusUp},
+		{name: "expiring", expiresIn: 5 * day, trusted: true, want: models.StatusDegraded, wantFindings: []string{"expiring"}},
+		{name: "custom expiry warning", expiresIn: 20 * day, trusted: true, config: map[string]interface{}{"expiry_warning_days": 30}, want: models.StatusDegraded, wantFindings: []string{"expiring"}},
+		{name: "expired", expiresIn: -day, trusted: true, want: models.StatusDown, wantFindings: []string{"invalid_certificate", "expired"}},
+		{name: "untrusted", expiresIn: 365 * day, want: models.StatusDown, wantFindings: []string{"untrusted_root"}},
+		{name: "hostname mismatch", expiresIn: 365 * day, trusted: true, config: map[string]interface{}{"server_name": "other.test"}, want: models.StatusDown, wantFindings: []string{"hostname_mismatch"}},
+		{name: "weak protocol", expiresIn: 365 * day, trusted: true, minVersion: tls.VersionTLS10, want: models.StatusDegraded, wantFindings: []string{"weak_protocol"}},
+		{name: "expected issuer", expiresIn: 365 * day, trusted: true, config: map[string]interface{}{"expected_issuers": []interface{}{"updawg test"}}, want: models.StatusUp},
+		{name: "unexpected issuer", expiresIn: 365 * day, trusted: true, config: map[string]interface{}{"expected_issuers": []interface{}{"Let's Encrypt"}}, want: models.StatusDown, wantFindings: []string{"unexpected_ca"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cert, certPem := newTestCert(t, time.Now().Add(tt.expiresIn))
+
+			server := httptest.NewUnstartedServer(http.NotFoundHandler())
+			server.TLS = &tls.Config{Certificates: []tls.Certificate{cert}, MinVersion: tt.minVersion}
+			server.StartTLS()
+			defer server.Close()
+
+			target := models.Target{Kind: models.TargetKindTls, Uri: server.Listener.Addr().String()}
+			if tt.trusted {
+				caFile := filepath.Join(t.TempDir(), "ca.pem")
+				if err := os.WriteFile(caFile, certPem, 0o600); err != nil {
+					t.Fatal(err)
+				}
+
+				target.SetConfig(map[string]interface{}{"ca_file": caFile})
+			}
+			target.SetConfig(tt.config)
+
+			result := checkTls(context.Background(), nil, &target)
+			if result.Status != tt.want {
+				t.Errorf("status = %s, want %s (message %q)", result.Status, tt.want, result.Message)
+			}
+
+			var findings []string
+			for _, finding := range result.Data["findings"].([]map[string]interface{}) {
+				findings = append(findings, finding["code"].(string))
+			}
+
+			if !slices.Equal(findings, tt.wantFindings) {
+				t.Errorf("findings = %v, want %v", findings, tt.wantFindings)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package checks
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// httpTimings records the phases of an HTTP request. Phases which didn't
+// happen, such as DNS for an IP address or any phase on a reused connection,
+// are left zero. Hooks may run concurrently, as addresses of both IP
+// families can be dialled at once, so fields are guarded by mu.
+type httpTimings struct {
+	mu           sync.Mutex
+	start        time.Time
+	dnsStart     time.Time
+	dns          time.Duration
+	connectStart time.Time
+	connect      time.Duration
+	tlsStart     time.Time
+	tls          time.Duration
+	firstByte    time.Duration
+	total        time.Duration
+}
+
+// withHttpTimings returns a context which records timings for requests made
+// with it.
+func withHttpTimings(ctx context.Context) (context.Context, *httpTimings) {
+	t := &httpTimings{start: time.Now()}
+
+	// record runs f with the timings locked
+	record := func(f func()) {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		f()
+	}
+
+	trace := &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			record(func() { t.dnsStart = time.Now() })
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			record(func() { t.dns = time.Since(t.dnsStart) })
+		},
+		ConnectStart: func(string, string) {
+			record(func() {
+				if t.connectStart.IsZero() {
+					t.connectStart = time.Now()
+				}
+			})
+		},
+		// Only the first connection to succeed is used, the others having
+		// failed or been cancelled
+		ConnectDone: func(_, _ string, err error) {
+			record(func() {
+				if err == nil && t.connect == 0 {
+					t.connect = time.Since(t.connectStart)
+				}
+			})
+		},
+		TLSHandshakeStart: func() {
+			record(func() { t.tlsStart = time.Now() })
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			record(func() { t.tls = time.Since(t.tlsStart) })
+		},
+		GotFirstResponseByte: func() {
+			record(func() { t.firstByte = time.Since(t.start) })
+		},
+	}
+
+	return httptrace.WithClientTrace(ctx, trace), t
+}
+
+// done marks the end of the request, after the body has been read.
+func (t *httpTimings) done() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.total = time.Since(t.start)
+}
+
+// data returns the timings in milliseconds for storing in a check result.
+// transfer is the time spent reading the body after the first byte.
+func (t *httpTimings) data() map[string]interface{} {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	ms := func(d time.Duration) float64 {
+		return float64(d.Microseconds()) / 1000
+	}
+
+	data := map[string]interface{}{
+		"dns_ms":        ms(t.dns),
+		"connect_ms":    ms(t.connect),
+		"tls_ms":        ms(t.tls),
+		"first_byte_ms": ms(t.firstByte),
+		"total_ms":      ms(t.total),
+	}
+
+	if t.firstByte != 0 {
+		data["transfer_ms"] = ms(t.total - t.firstByte)
+	}
+
+	return data
+}
@@ -0,0 +1,105 @@
+package checks
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestHttpTimings(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+
+	tests := []struct {
+		name   string
+		server *httptest.Server
+		// host replaces the server's address, to look it up
+		host    string
+		wantDns bool
+		wantTls bool
+	}{
+		{name: "ip address", server: httptest.NewServer(handler)},
+		{name: "hostname", server: httptest.NewServer(handler), host: "localhost", wantDns: true},
+		{name: "tls", server: httptest.NewTLSServer(handler), wantTls: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer tt.server.Close()
+
+			uri := tt.server.URL
+			if tt.host != "" {
+				uri = strings.Replace(uri, "127.0.0.1", tt.host, 1)
+			}
+
+			ctx, timings := withHttpTimings(context.Background())
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			res, err := tt.server.Client().Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			io.Copy(io.Discard, res.Body)
+			res.Body.Close()
+			timings.done()
+
+			if (timings.dns > 0) != tt.wantDns {
+				t.Errorf("dns = %s, want it recorded: %v", timings.dns, tt.wantDns)
+			}
+
+			if (timings.tls > 0) != tt.wantTls {
+				t.Errorf("tls = %s, want it recorded: %v", timings.tls, tt.wantTls)
+			}
+
+			if timings.connect <= 0 || timings.firstByte <= 0 || timings.total < timings.firstByte {
+				t.Errorf("connect = %s, first byte = %s, total = %s, want them recorded in order", timings.connect, timings.firstByte, timings.total)
+			}
+
+			data := timings.data()
+			for _, key := range []string{"dns_ms", "connect_ms", "tls_ms", "first_byte_ms", "total_ms", "transfer_ms"} {
+				if _, ok := data[key]; !ok {
+					t.Errorf("data is missing %s", key)
+				}
+			}
+		})
+	}
+}
+
+func TestHttpTimingsParallelDials(t *testing.T) {
+	ctx, timings := withHttpTimings(context.Background())
+	trace := httptrace.ContextClientTrace(ctx)
+
+	// Both addresses are dialled at once, and the fallback fails once the
+	// first connects
+	var dials sync.WaitGroup
+	for _, addr := range []string{"[::1]:80", "127.0.0.1:80"} {
+		dials.Add(1)
+		go func() {
+			defer dials.Done()
+			trace.ConnectStart("tcp", addr)
+		}()
+	}
+	dials.Wait()
+
+	time.Sleep(time.Millisecond)
+	trace.ConnectDone("tcp", "127.0.0.1:80", nil)
+	connect := timings.connect
+
+	time.Sleep(time.Millisecond)
+	trace.ConnectDone("tcp", "[::1]:80", errors.New("operation was canceled"))
+
+	if connect <= 0 || timings.connect != connect {
+		t.Errorf("connect = %s then %s after the fallback failed, want it kept", connect, timings.connect)
+	}
+}
@@ -0,0 +1,277 @@
+package checks
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tehlordvortex/updawg/config"
+	"github.com/tehlordvortex/updawg/models"
+)
+
+const maxTransactionBodySize = 1 << 20
+
+var transactionVariable = regexp.MustCompile(`{{\s*([A-Za-z0-9_]+)\s*}}`)
+
+type transactionConfig struct {
+	Steps []transactionStep `json:"steps"`
+	// Variables are available to every step and are overwritten by
+	// extracted values.
+	Variables map[string]string `json:"variables"`
+	// Timeout is the number of seconds each step may take.
+	Timeout int64 `json:"timeout"`
+}
+
+type transactionStep struct {
+	Name    string            `json:"name"`
+	Method  string            `json:"method"`
+	Url     string            `json:"url"`
+	Headers map[string]string `json:"headers"`
+	Body    string            `json:"body"`
+	// ExpectStatus defaults to config.DefaultResponseCode.
+	ExpectStatus int `json:"expect_status"`
+	// ExpectBody is a regular expression the response body must match.
+	ExpectBody string                          `json:"expect_body"`
+	Extract    map[string]transactionExtractor `json:"extract"`
+}
+
+// transactionExtractor captures a value from a response. Exactly one of its
+// fields should be set. Regex uses the first capture group if there is one.
+type transactionExtractor struct {
+	Header string `json:"header"`
+	Json   string `json:"json"`
+	Regex  string `json:"regex"`
+}
+
+// checkTransaction runs an ordered sequence of HTTP requests sharing a
+// cookie jar. Relative step urls are resolved against the target's uri.
+func checkTransaction(ctx context.Context, qe models.QueryExecutor, target *models.Target) models.CheckResult {
+	var cfg transactionConfig
+	if err := target.DecodeConfig(&cfg); err != nil {
+		return unknown("invalid config: %v", err)
+	}
+
+	if len(cfg.Steps) == 0 {
+		return unknown("invalid config: no steps")
+	}
+
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = config.DefaultTimeout
+	}
+
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return unknown("%v", err)
+	}
+
+	client := &http.Client{Jar: jar, Timeout: time.Duration(cfg.Timeout) * time.Second}
+	variables := make(map[string]string)
+	for name, value := range cfg.Variables {
+		variables[name] = value
+	}
+
+	var steps []map[string]interface{}
+	result := up("%d steps completed", len(cfg.Steps))
+
+	for i, step := range cfg.Steps {
+		if step.Name == "" {
+			step.Name = strconv.Itoa(i + 1)
+		}
+
+		stepCtx, timings := withHttpTimings(ctx)
+		statusCode, err := runTransactionStep(stepCtx, client, target.Uri, step, variables)
+		timings.done()
+
+		stepData := map[string]interface{}{
+			"name":        step.Name,
+			"duration_ms": timings.total.Milliseconds(),
+			"timings":     timings.data(),
+		}
+		if statusCode != 0 {
+			stepData["status_code"] = statusCode
+		}
+		steps = append(steps, stepData)
+
+		if err != nil {
+			stepData["error"] = err.Error()
+			result = down("step %s: %v", step.Name, err)
+			break
+		}
+	}
+
+	result.Data["steps"] = steps
+
+	return result
+}
+
+func runTransactionStep(ctx context.Context, client *http.Client, baseUri string, step transactionStep, variables map[string]string) (int, error) {
+	substitute := func(s string) string {
+		return transactionVariable.ReplaceAllStringFunc(s, func(match string) string {
+			name := transactionVariable.FindStringSubmatch(match)[1]
+			return variables[name]
+		})
+	}
+
+	stepUrl, err := resolveStepUrl(baseUri, substitute(step.Url))
+	if err != nil {
+		return 0, err
+	}
+
+	method := step.Method
+	if method == "" {
+		method = http.MethodGet
+	}
+
+	var body io.Reader
+	if step.Body != "" {
+		body = strings.NewReader(substitute(step.Body))
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, stepUrl, body)
+	if err != nil {
+		return 0, err
+	}
+
+	for name, value := range step.Headers {
+		req.Header.Set(name, substitute(value))
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+
+	resBody, err := io.ReadAll(io.LimitReader(res.Body, maxTransactionBodySize))
+	if err != nil {
+		return res.StatusCode, err
+	}
+
+	expectStatus := step.ExpectStatus
+	if expectStatus == 0 {
+		expectStatus = config.DefaultResponseCode
+	}
+
+	if res.StatusCode != expectStatus {
+		return res.StatusCode, fmt.Errorf("unexpected status %d", res.StatusCode)
+	}
+
+	if step.ExpectBody != "" {
+		re, err := regexp.Compile(step.ExpectBody)
+		if err != nil {
+			return res.StatusCode, fmt.Errorf("invalid expect_body: %v", err)
+		}
+
+		if !re.Match(resBody) {
+			return res.StatusCode, fmt.Errorf("body does not match %q", step.ExpectBody)
+		}
+	}
+
+	for name, extractor := range step.Extract {
+		value, err := extractor.extract(res, resBody)
+		if err != nil {
+			return res.StatusCode, fmt.Errorf("extract %s: %v", name, err)
+		}
+
+		variables[name] = value
+	}
+
+	return res.StatusCode, nil
+}
+
+func resolveStepUrl(baseUri, stepUrl string) (string, error) {
+	if baseUri == "" {
+		return stepUrl, nil
+	}
+
+	base, err := url.Parse(baseUri)
+	if err != nil {
+		return "", err
+	}
+
+	ref, err := url.Parse(stepUrl)
+	if err != nil {
+		return "", err
+	}
+
+	return base.ResolveReference(ref).String(), nil
+}
+
+func (e transactionExtractor) extract(res *http.Response, body []byte) (string, error) {
+	switch {
+	case e.Header != "":
+		value := res.Header.Get(e.Header)
+		if value == "" {
+			return "", fmt.Errorf("header %s not found", e.Header)
+		}
+
+		return value, nil
+	case e.Json != "":
+		var v interface{}
+		if err := json.Unmarshal(body, &v); err != nil {
+			return "", err
+		}
+
+		return lookupJsonPath(v, e.Json)
+	case e.Regex != "":
+		re, err := regexp.Compile(e.Regex)
+		if err != nil {
+			return "", err
+		}
+
+		match := re.FindSubmatch(body)
+		if match == nil {
+			return "", fmt.Errorf("%q did not match", e.Regex)
+		}
+
+		// Use the first capture group, or the whole match without one
+		if len(match) > 1 {
+			return string(match[1]), nil
+		}
+
+		return string(match[0]), nil
+	default:
+		return "", fmt.Errorf("no header, json or regex given")
+	}
+}
+
+// lookupJsonPath follows a dot separated path such as data.items.0.id
+// through decoded JSON.
+func lookupJsonPath(v interface{}, path string) (string, error) {
+	for _, key := range strings.Split(path, ".") {
+		switch node := v.(type) {
+		case map[string]interface{}:
+			next, ok := node[key]
+			if !ok {
+				return "", fmt.Errorf("%s not found", path)
+			}
+			v = next
+		case []interface{}:
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= len(node) {
+				return "", fmt.Errorf("%s not found", path)
+			}
+			v = node[i]
+		default:
+			return "", fmt.Errorf("%s not found", path)
+		}
+	}
+
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case nil:
+		return "", nil
+	default:
+		encoded, err := json.Marshal(v)
+		return string(encoded), err
+	}
+}
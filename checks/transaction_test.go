@@ -0,0 +1,89 @@
+package checks
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestTransactionExtractorExtract(t *testing.T) {
+	res := &http.Response{Header: http.Header{}}
+	res.Header.Set("Location", "/sessions/42")
+	body := []byte(`{"token":"abc","user":{"id":7}} csrf=xyz123;`)
+	jsonBody := []byte(`{"token":"abc","user":{"id":7}}`)
+
+	tests := []struct {
+		name      string
+		extractor transactionExtractor
+		body      []byte
+		want      string
+		wantErr   bool
+	}{
+		{name: "header", extractor: transactionExtractor{Header: "Location"}, body: body, want: "/sessions/42"},
+		{name: "missing header", extractor: transactionExtractor{Header: "X-Missing"}, body: body, wantErr: true},
+		{name: "json", extractor: transactionExtractor{Json: "user.id"}, body: jsonBody, want: "7"},
+		{name: "invalid json", extractor: transactionExtractor{Json: "token"}, body: []byte("not json"), wantErr: true},
+		{name: "regex first capture group", extractor: transactionExtractor{Regex: `csrf=(\w+)(;)`}, body: body, want: "xyz123"},
+		{name: "regex whole match", extractor: transactionExtractor{Regex: `csrf=\w+`}, body: body, want: "csrf=xyz123"},
+		{name: "regex no match", extractor: transactionExtractor{Regex: `session=(\w+)`}, body: body, wantErr: true},
+		{name: "invalid regex", extractor: transactionExtractor{Regex: `(`}, body: body, wantErr: true},
+		{name: "nothing set", extractor: transactionExtractor{}, body: body, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.extractor.extract(res, tt.body)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("extract() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Errorf("extract() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLookupJsonPath(t *testing.T) {
+	var doc interface{}
+	if err := json.Unmarshal([]byte(`{
+		"name": "updawg",
+		"count": 3,
+		"ok": true,
+		"missing": null,
+		"data": {"items": [{"id": "first"}, {"id": "second"}]}
+	}`), &doc); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		path    string
+		want    string
+		wantErr bool
+	}{
+		{path: "name", want: "updawg"},
+		{path: "count", want: "3"},
+		{path: "ok", want: "true"},
+		{path: "missing", want: ""},
+		{path: "data.items.1.id", want: "second"},
+		{path: "data.items.0", want: `{"id":"first"}`},
+		{path: "data.items.2.id", wantErr: true},
+		{path: "data.items.-1", wantErr: true},
+		{path: "data.items.first", wantErr: true},
+		{path: "name.length", wantErr: true},
+		{path: "nope", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			got, err := lookupJsonPath(doc, tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("lookupJsonPath(%q) error = %v, wantErr %v", tt.path, err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Errorf("lookupJsonPath(%q) = %q, want %q", tt.path, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package checks
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"net"
+	"os"
+	"time"
+
+	"github.com/tehlordvortex/updawg/config"
+	"github.com/tehlordvortex/updawg/models"
+)
+
+const maxUdpResponseSize = 65535
+
+type udpConfig struct {
+	// Payload and PayloadHex are the datagram to send, as text or hex.
+	Payload    string `json:"payload"`
+	PayloadHex string `json:"payload_hex"`
+	// Expect and ExpectHex must be contained in the response. Setting either
+	// implies ExpectResponse.
+	Expect    string `json:"expect"`
+	ExpectHex string `json:"expect_hex"`
+	// ExpectResponse treats not receiving a response as down.
+	ExpectResponse bool  `json:"expect_response"`
+	Timeout        int64 `json:"timeout"`
+}
+
+// checkUdp sends a datagram to the target and optionally waits for a
+// response. Without an expected response a successful send is up.
+func checkUdp(ctx context.Context, qe models.QueryExecutor, target *models.Target) models.CheckResult {
+	var cfg udpConfig
+	if err := target.DecodeConfig(&cfg); err != nil {
+		return unknown("invalid config: %v", err)
+	}
+
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = config.DefaultTimeout
+	}
+
+	payload := []byte(cfg.Payload)
+	if cfg.PayloadHex != "" {
+		var err error
+		if payload, err = hex.DecodeString(cfg.PayloadHex); err != nil {
+			return unknown("invalid payload_hex: %v", err)
+		}
+	}
+
+	expect := []byte(cfg.Expect)
+	if cfg.ExpectHex != "" {
+		var err error
+		if expect, err = hex.DecodeString(cfg.ExpectHex); err != nil {
+			return unknown("invalid expect_hex: %v", err)
+		}
+	}
+
+	expectResponse := cfg.ExpectResponse || len(expect) > 0
+
+	_, host, port, err := parseAddress(target.Uri, "")
+	if err != nil || port == "" {
+		return unknown("invalid uri: must be host:port")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(cfg.Timeout)*time.Second)
+	defer cancel()
+
+	conn, err := dialContext(ctx, "udp", net.JoinHostPort(host, port))
+	if err != nil {
+		return down("%v", err)
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	if _, err := conn.Write(payload); err != nil {
+		return down("send failed: %v", err)
+	}
+
+	if !expectResponse {
+		return up("sent %d bytes", len(payload))
+	}
+
+	buf := make([]byte, maxUdpResponseSize)
+	n, err := conn.Read(buf)
+	if err != nil {
+		if errors.Is(err, os.ErrDeadlineExceeded) {
+			return down("no response within %ds", cfg.Timeout)
+		}
+
+		return down("receive failed: %v", err)
+	}
+	response := buf[:n]
+
+	var result models.CheckResult
+	if !bytes.Contains(response, expect) {
+		result = down("unexpected response")
+	} else {
+		result = up("received %d bytes", n)
+	}
+
+	result.Data["response_hex"] = hex.EncodeToString(response[:min(n, 64)])
+
+	return result
+}
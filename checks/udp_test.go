@@ -0,0 +1,73 @@
+package checks
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"testing"
+
+	"github.com/tehlordvortex/updawg/models"
+)
+
+// serveUdpEcho echoes datagrams back with "echo: " prepended, ignoring any
+// starting with "ignore".
+func serveUdpEcho(t *testing.T) string {
+	t.Helper()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		buf := make([]byte, 1500)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+
+			if !bytes.HasPrefix(buf[:n], []byte("ignore")) {
+				conn.WriteTo(append([]byte("echo: "), buf[:n]...), addr)
+			}
+		}
+	}()
+
+	return conn.LocalAddr().String()
+}
+
+func TestCheckUdp(t *testing.T) {
+	addr := serveUdpEcho(t)
+
+	tests := []struct {
+		name   string
+		uri    string
+		config map[string]interface{}
+		want   models.Status
+	}{
+		{name: "sent without waiting", config: map[string]interface{}{"payload": "ignore me"}, want: models.StatusUp},
+		{name: "expected response", config: map[string]interface{}{"payload": "ping", "expect": "echo: ping"}, want: models.StatusUp},
+		{name: "expected response in hex", config: map[string]interface{}{"payload_hex": "0102", "expect_hex": "0102"}, want: models.StatusUp},
+		{name: "unexpected response", config: map[string]interface{}{"payload": "ping", "expect": "pong"}, want: models.StatusDown},
+		{name: "any response", config: map[string]interface{}{"payload": "ping", "expect_response": true}, want: models.StatusUp},
+		{name: "no response", config: map[string]interface{}{"payload": "ignore me", "expect_response": true, "timeout": 1}, want: models.StatusDown},
+		{name: "invalid hex", config: map[string]interface{}{"payload_hex": "zz"}, want: models.StatusUnknown},
+		{name: "missing port", uri: "127.0.0.1", want: models.StatusUnknown},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := models.Target{Kind: models.TargetKindUdp, Uri: addr}
+			if tt.uri != "" {
+				target.Uri = tt.uri
+			}
+			target.SetConfig(tt.config)
+
+			result := checkUdp(context.Background(), nil, &target)
+			if result.Status != tt.want {
+				t.Errorf("status = %s, want %s (message %q)", result.Status, tt.want, result.Message)
+			}
+		})
+	}
+}
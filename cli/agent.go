@@ -0,0 +1,49 @@
+package cli
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"time"
+
+	"github.com/tehlordvortex/updawg/agent"
+	"github.com/tehlordvortex/updawg/config"
+	"github.com/tehlordvortex/updawg/workers"
+)
+
+func runAgentCommand(ctx context.Context, db *sql.DB, args []string) {
+	fs := flag.NewFlagSet("agent", flag.ExitOnError)
+	server := fs.String("server", config.GetBaseUrl(), "The URL of the updawg serve instance to report to")
+	token := fs.String("token", config.GetAgentToken(), "The token shared with the server")
+	location := fs.String("location", config.GetLocation(), "The location targets are checked from, as listed in their locations config")
+	poolSize := fs.Int("workers", config.GetWorkerCount(), "The number of checks to run at once")
+	refresh := fs.Duration("refresh", 30*time.Second, "How often to fetch the targets assigned to the location")
+
+	if err := fs.Parse(args); err != nil {
+		logger.Fatalln(err)
+	}
+
+	if *location == "" {
+		logger.Fatalln("a location is required")
+	}
+
+	if *token == "" {
+		logger.Fatalln("a token is required")
+	}
+
+	if *refresh <= 0 {
+		logger.Fatalln("refresh must be positive")
+	}
+
+	client := agent.NewClient(*server, *token, *location)
+	id, err := client.Register(ctx)
+	if err != nil {
+		logger.Fatalln(err)
+	}
+
+	logger.Printf("registered agent id=%s location=%s server=%s\n", id, *location, *server)
+	workers.RunAgent(ctx, db, client, workers.AgentOptions{
+		PoolSize: *poolSize,
+		Refresh:  *refresh,
+	})
+}
@@ -35,6 +35,8 @@ func Run(ctx context.Context, db *sql.DB) {
 		runTargetsCommand(ctx, db, subArgs)
 	case "serve":
 		runServeCommand(ctx, db, subArgs)
+	case "agent":
+		runAgentCommand(ctx, db, subArgs)
 	default:
 		logger.Println("unknown command:", command)
 		printUsage()
@@ -47,5 +49,7 @@ func Run(ctx context.Context, db *sql.DB) {
 func printUsage() {
 	fmt.Fprintf(flag.CommandLine.Output(), Header)
 	fmt.Fprintf(flag.CommandLine.Output(), "targets\t\tManage monitoring targets\n")
+	fmt.Fprintf(flag.CommandLine.Output(), "serve\t\tRun monitoring and serve heartbeat pings\n")
+	fmt.Fprintf(flag.CommandLine.Output(), "agent\t\tCheck targets from this location and report to serve\n")
 	flag.PrintDefaults()
 }
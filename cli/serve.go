@@ -3,12 +3,48 @@ package cli
 import (
 	"context"
 	"database/sql"
+	"flag"
+	"sync"
 
+	"github.com/tehlordvortex/updawg/config"
+	"github.com/tehlordvortex/updawg/server"
 	"github.com/tehlordvortex/updawg/workers"
 )
 
 func runServeCommand(ctx context.Context, db *sql.DB, args []string) {
-	go workers.Run(ctx, db)
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := fs.String("addr", config.GetListenAddr(), "The address to serve heartbeat pings on")
+	poolSize := fs.Int("workers", config.GetWorkerCount(), "The number of checks to run at once")
+	hostConcurrency := fs.Int("host-concurrency", config.GetHostConcurrency(), "The number of checks to run against a single host at once (0 for no limit)")
+	rate := fs.Float64("rate", config.GetRate(), "The number of checks to start per second (0 for no limit)")
+	hostRate := fs.Float64("host-rate", config.GetHostRate(), "The number of checks to start per second against a single host (0 for no limit)")
+	leaseTtl := fs.Duration("lease-ttl", config.GetLeaseTtl(), "How long before other instances take over this instance's targets if it stops responding")
+	shutdownTimeout := fs.Duration("shutdown-timeout", config.GetShutdownTimeout(), "How long to wait for checks in progress to finish when stopping")
 
-	<-ctx.Done()
+	if err := fs.Parse(args); err != nil {
+		logger.Fatalln(err)
+	}
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+
+	go func() {
+		defer wg.Done()
+		workers.Run(ctx, db, workers.Options{
+			PoolSize:        *poolSize,
+			HostConcurrency: *hostConcurrency,
+			Rate:            *rate,
+			HostRate:        *hostRate,
+			ShutdownTimeout: *shutdownTimeout,
+			LeaseTtl:        *leaseTtl,
+		})
+	}()
+
+	go func() {
+		defer wg.Done()
+		server.Run(ctx, db, *addr)
+	}()
+
+	// Both stop once ctx is done, after finishing what they're doing
+	wg.Wait()
 }
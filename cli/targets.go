@@ -3,12 +3,16 @@ package cli
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/tehlordvortex/updawg/config"
 	"github.com/tehlordvortex/updawg/models"
+	"github.com/tehlordvortex/updawg/pubsub"
 )
 
 func runTargetsCommand(ctx context.Context, db *sql.DB, args []string) {
@@ -36,6 +40,20 @@ func runTargetsCommand(ctx context.Context, db *sql.DB, args []string) {
 		runListCommand(ctx, db, subArgs)
 	case "delete":
 		runDeleteCommand(ctx, db, subArgs)
+	case "results":
+		runResultsCommand(ctx, db, subArgs)
+	case "changes":
+		runChangesCommand(ctx, db, subArgs)
+	case "tls":
+		runTlsCommand(ctx, db, subArgs)
+	case "uptime":
+		runUptimeCommand(ctx, db, subArgs)
+	case "check":
+		runCheckCommand(ctx, db, subArgs)
+	case "pause":
+		runPauseCommand(ctx, db, subArgs)
+	case "resume":
+		runResumeCommand(ctx, db, subArgs)
 	default:
 		logger.Println("unknown command:", command)
 		printTargetsUsage(fs)
@@ -50,6 +68,13 @@ func printTargetsUsage(fs *flag.FlagSet) {
 	fmt.Fprintln(flag.CommandLine.Output(), "modify\t\tModify a target")
 	fmt.Fprintln(flag.CommandLine.Output(), "list\t\tList existing targets")
 	fmt.Fprintln(flag.CommandLine.Output(), "delete\t\tDelete a target")
+	fmt.Fprintln(flag.CommandLine.Output(), "results\t\tShow recent check results for a target")
+	fmt.Fprintln(flag.CommandLine.Output(), "changes\t\tShow content change history for a content target")
+	fmt.Fprintln(flag.CommandLine.Output(), "tls\t\tShow the latest TLS audit of every tls target")
+	fmt.Fprintln(flag.CommandLine.Output(), "uptime\t\tShow a target's uptime within its active hours")
+	fmt.Fprintln(flag.CommandLine.Output(), "check\t\tCheck a target now and show the result (requires updawg serve)")
+	fmt.Fprintln(flag.CommandLine.Output(), "pause\t\tStop monitoring a target, optionally until a set time")
+	fmt.Fprintln(flag.CommandLine.Output(), "resume\t\tResume monitoring a paused target")
 	fs.PrintDefaults()
 	flag.PrintDefaults()
 }
@@ -57,24 +82,42 @@ func printTargetsUsage(fs *flag.FlagSet) {
 func runCreateCommand(ctx context.Context, db *sql.DB, args []string) {
 	fs := flag.NewFlagSet("targets create", flag.ExitOnError)
 	name := fs.String("name", "", "An optional name for the target")
-	uri := fs.String("uri", "", "The URI to make requests to")
+	uri := fs.String("uri", "", "The URI to make requests to (the command to run for exec targets; sql targets take a dsn in -config)")
 	method := fs.String("method", config.DefaultMethod, "The HTTP method to use")
+	kind := fs.String("kind", models.TargetKindHttp, "The kind of target ("+strings.Join(models.TargetKinds, ", ")+")")
 	period := fs.Uint("period", config.DefaultPeriod, "The interval (in seconds) in which requests are made")
+	targetConfig := fs.String("config", "", "Kind specific configuration as a JSON object")
+	dependsOn := fs.String("depends-on", "", "Comma separated IDs of targets this target depends on (can be partial)")
+	schedule := fs.String("schedule", "", "A cron expression to check on instead of every period")
+	activeHours := fs.String("active-hours", "", "Only check within these hours, e.g. \"mon-fri 09:00-17:00\"")
+	timezone := fs.String("timezone", "", "The time zone of the schedule and active hours (default local)")
 
 	if err := fs.Parse(args); err != nil {
 		logger.Fatalln(err)
 	}
 
-	if *uri == "" {
-		fs.Usage()
-		os.Exit(1)
+	target := models.Target{
+		Name:        *name,
+		Uri:         *uri,
+		Method:      *method,
+		Kind:        *kind,
+		Period:      int64(*period),
+		Schedule:    *schedule,
+		ActiveHours: *activeHours,
+		Timezone:    *timezone,
 	}
 
-	target := models.Target{
-		Name:   *name,
-		Uri:    *uri,
-		Method: *method,
-		Period: int64(*period),
+	if *dependsOn != "" {
+		target.ParentIds = findTargetIdsByIdPrefixes(ctx, db, *dependsOn)
+	}
+
+	if *targetConfig != "" {
+		cfg, err := parseConfigFlag(*targetConfig)
+		if err != nil {
+			logger.Fatalln(err)
+		}
+
+		target.SetConfig(cfg)
 	}
 
 	if err := target.Save(ctx, db); err != nil {
@@ -82,15 +125,24 @@ func runCreateCommand(ctx context.Context, db *sql.DB, args []string) {
 	}
 
 	logger.Println("target created id=" + target.Id())
+	if target.Kind == models.TargetKindHeartbeat {
+		logger.Println("ping url=" + pingUrl(&target))
+	}
 }
 
 func runModifyCommand(ctx context.Context, db *sql.DB, args []string) {
 	fs := flag.NewFlagSet("targets modify", flag.ExitOnError)
 	id := fs.String("id", "", "The ID of the target to modify (can be partial)")
 	name := fs.String("name", "", "An optional name for the target")
-	uri := fs.String("uri", "", "The URI to make requests to")
+	uri := fs.String("uri", "", "The URI to make requests to (the command to run for exec targets; sql targets take a dsn in -config)")
 	method := fs.String("method", config.DefaultMethod, "The HTTP method to use")
+	kind := fs.String("kind", "", "The kind of target ("+strings.Join(models.TargetKinds, ", ")+")")
 	period := fs.Uint("period", config.DefaultPeriod, "The interval (in seconds) in which requests are made")
+	targetConfig := fs.String("config", "", "Kind specific configuration as a JSON object, merged into the existing configuration")
+	dependsOn := fs.String("depends-on", "", "Comma separated IDs of targets this target depends on (can be partial), empty to remove all")
+	schedule := fs.String("schedule", "", "A cron expression to check on instead of every period, empty to remove")
+	activeHours := fs.String("active-hours", "", "Only check within these hours, e.g. \"mon-fri 09:00-17:00\", empty to remove")
+	timezone := fs.String("timezone", "", "The time zone of the schedule and active hours, empty for local")
 
 	if err := fs.Parse(args); err != nil {
 		logger.Fatalln(err)
@@ -101,18 +153,7 @@ func runModifyCommand(ctx context.Context, db *sql.DB, args []string) {
 		os.Exit(1)
 	}
 
-	targets, err := models.FindTargetsByIdPrefix(ctx, db, *id)
-	if err != nil {
-		logger.Fatalln(err)
-	}
-
-	if len(targets) == 0 {
-		logger.Fatalln("not found:", id)
-	} else if len(targets) != 1 {
-		logger.Fatalln(id, "is ambiguous")
-	}
-
-	target := targets[0]
+	target := findTargetByIdPrefix(ctx, db, *id)
 
 	if *name != "" {
 		target.Name = *name
@@ -126,15 +167,44 @@ func runModifyCommand(ctx context.Context, db *sql.DB, args []string) {
 		target.Method = *method
 	}
 
+	if *kind != "" {
+		target.Kind = *kind
+	}
+
 	if *period != 0 {
 		target.Period = int64(*period)
 	}
 
+	if *targetConfig != "" {
+		cfg, err := parseConfigFlag(*targetConfig)
+		if err != nil {
+			logger.Fatalln(err)
+		}
+
+		target.SetConfig(cfg)
+	}
+
+	if isFlagSet(fs, "depends-on") {
+		target.ParentIds = findTargetIdsByIdPrefixes(ctx, db, *dependsOn)
+	}
+
+	if isFlagSet(fs, "schedule") {
+		target.Schedule = *schedule
+	}
+
+	if isFlagSet(fs, "active-hours") {
+		target.ActiveHours = *activeHours
+	}
+
+	if isFlagSet(fs, "timezone") {
+		target.Timezone = *timezone
+	}
+
 	if err := target.Save(ctx, db); err != nil {
 		logger.Fatalln(err)
 	}
 
-	logger.Println("target updated:", target)
+	logger.Println("target updated:", &target)
 }
 
 func runListCommand(ctx context.Context, db *sql.DB, args []string) {
@@ -144,7 +214,7 @@ func runListCommand(ctx context.Context, db *sql.DB, args []string) {
 	}
 
 	for _, target := range targets {
-		logger.Println(target)
+		logger.Println(&target)
 	}
 }
 
@@ -155,21 +225,384 @@ func runDeleteCommand(ctx context.Context, db *sql.DB, args []string) {
 
 	id := args[0]
 
-	targets, err := models.FindTargetsByIdPrefix(ctx, db, id)
+	target := findTargetByIdPrefix(ctx, db, id)
+	if err := target.Delete(ctx, db); err != nil {
+		logger.Fatalln(err)
+	}
+
+	logger.Println("deleted:", &target)
+}
+
+func runPauseCommand(ctx context.Context, db *sql.DB, args []string) {
+	fs := flag.NewFlagSet("targets pause", flag.ExitOnError)
+	duration := fs.Duration("for", 0, "Resume the target automatically after this long")
+	until := fs.String("until", "", "Resume the target automatically at this time (RFC 3339)")
+
+	if err := fs.Parse(args); err != nil {
+		logger.Fatalln(err)
+	}
+
+	if fs.NArg() == 0 {
+		logger.Fatalln("missing target id")
+	}
+
+	if *duration != 0 && *until != "" {
+		logger.Fatalln("-for and -until can't be used together")
+	} else if *duration < 0 {
+		logger.Fatalln("invalid for: must be positive")
+	}
+
+	var pausedUntil time.Time
+	if *duration > 0 {
+		pausedUntil = time.Now().Add(*duration)
+	} else if *until != "" {
+		var err error
+		if pausedUntil, err = time.Parse(time.RFC3339, *until); err != nil {
+			logger.Fatalln("invalid until:", err)
+		} else if !pausedUntil.After(time.Now()) {
+			logger.Fatalln("invalid until: must be in the future")
+		}
+	}
+
+	target := findTargetByIdPrefix(ctx, db, fs.Arg(0))
+	if err := target.Pause(ctx, db, pausedUntil); err != nil {
+		logger.Fatalln(err)
+	}
+
+	logger.Println("paused:", &target)
+}
+
+func runResumeCommand(ctx context.Context, db *sql.DB, args []string) {
+	fs := flag.NewFlagSet("targets resume", flag.ExitOnError)
+
+	if err := fs.Parse(args); err != nil {
+		logger.Fatalln(err)
+	}
+
+	if fs.NArg() == 0 {
+		logger.Fatalln("missing target id")
+	}
+
+	target := findTargetByIdPrefix(ctx, db, fs.Arg(0))
+	if target.Enabled() {
+		logger.Fatalln("not paused:", &target)
+	}
+
+	if err := target.Resume(ctx, db); err != nil {
+		logger.Fatalln(err)
+	}
+
+	logger.Println("resumed:", &target)
+}
+
+func runResultsCommand(ctx context.Context, db *sql.DB, args []string) {
+	fs := flag.NewFlagSet("targets results", flag.ExitOnError)
+	id := fs.String("id", "", "The ID of the target (can be partial)")
+	limit := fs.Uint("limit", 20, "The number of results to show")
+
+	if err := fs.Parse(args); err != nil {
+		logger.Fatalln(err)
+	}
+
+	if *id == "" {
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	target := findTargetByIdPrefix(ctx, db, *id)
+
+	results, err := models.FindCheckResultsByTargetId(ctx, db, target.Id(), int(*limit))
+	if err != nil {
+		logger.Fatalln(err)
+	}
+
+	for _, result := range results {
+		logger.Println(&result)
+		printResultTimings(&result)
+	}
+}
+
+// runUptimeCommand reports the share of a target's checks which were up.
+// Checks outside the target's active hours, and those with no definite
+// result, are not counted.
+func runUptimeCommand(ctx context.Context, db *sql.DB, args []string) {
+	fs := flag.NewFlagSet("targets uptime", flag.ExitOnError)
+	id := fs.String("id", "", "The ID of the target (can be partial)")
+	since := fs.Duration("since", 24*time.Hour, "How far back to calculate uptime over")
+
+	if err := fs.Parse(args); err != nil {
+		logger.Fatalln(err)
+	}
+
+	if *id == "" {
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	target := findTargetByIdPrefix(ctx, db, *id)
+
+	sched, err := target.ParseSchedule()
+	if err != nil {
+		logger.Fatalln(err)
+	}
+
+	results, err := models.FindCheckResultsByTargetIdSince(ctx, db, target.Id(), time.Now().Add(-*since))
+	if err != nil {
+		logger.Fatalln(err)
+	}
+
+	var total, upCount int
+	for _, result := range results {
+		if !sched.IsActive(result.CreatedAt()) {
+			continue
+		}
+
+		switch result.Status {
+		case models.StatusUnknown, models.StatusUnreachable:
+			continue
+		case models.StatusUp:
+			upCount++
+		}
+
+		total++
+	}
+
+	if total == 0 {
+		logger.Printf("uptime id=%s since=%s checks=0\n", target.Id(), *since)
+		return
+	}
+
+	logger.Printf("uptime id=%s since=%s checks=%d up=%d uptime=%.3f%%\n", target.Id(), *since, total, upCount, float64(upCount)/float64(total)*100)
+}
+
+// runCheckCommand asks the running updawg serve to check a target now, and
+// waits for the result. It exits with an error if the check failed.
+func runCheckCommand(ctx context.Context, db *sql.DB, args []string) {
+	fs := flag.NewFlagSet("targets check", flag.ExitOnError)
+	timeout := fs.Duration("timeout", time.Minute, "How long to wait for the result")
+
+	if err := fs.Parse(args); err != nil {
+		logger.Fatalln(err)
+	}
+
+	if fs.NArg() == 0 {
+		logger.Fatalln("missing target id")
+	}
+
+	target := findTargetByIdPrefix(ctx, db, fs.Arg(0))
+	if target.Kind == models.TargetKindHeartbeat {
+		logger.Fatalln("heartbeat targets are checked by pings:", pingUrl(&target))
+	} else if !target.Enabled() {
+		// updawg serve ignores requests for paused targets
+		logger.Fatalln("target is paused")
+	}
+
+	// Subscribe first so the result can't be missed
+	results, unsub, err := pubsub.Subscribe(ctx, models.CheckResultCreatedTopic)
+	if err != nil {
+		logger.Fatalln(err)
+	}
+	defer unsub()
+
+	request, err := models.PublishCheckRequest(ctx, target.Id())
+	if err != nil {
+		logger.Fatalln(err)
+	}
+
+	timer := time.NewTimer(*timeout)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+			logger.Fatalf("no result within %s, is updawg serve running?\n", *timeout)
+		case message := <-results:
+			result, err := models.FindCheckResultById(ctx, db, message.Msg)
+			if err != nil {
+				logger.Println(err)
+				continue
+			}
+
+			if !request.IsResultFor(&result) {
+				continue
+			}
+
+			logger.Println(&result)
+			printResultTimings(&result)
+
+			if result.Status.IsFailure() {
+				os.Exit(1)
+			}
+
+			return
+		}
+	}
+}
+
+var timingPhases = []string{"dns", "connect", "tls", "first_byte", "transfer", "total"}
+
+// printResultTimings prints the per phase timings of HTTP results, and of
+// each step for transaction results.
+func printResultTimings(result *models.CheckResult) {
+	format := func(timings map[string]interface{}) string {
+		var phases []string
+		for _, phase := range timingPhases {
+			if ms, ok := timings[phase+"_ms"].(float64); ok {
+				phases = append(phases, fmt.Sprintf("%s=%.1fms", phase, ms))
+			}
+		}
+
+		return strings.Join(phases, " ")
+	}
+
+	if timings, ok := result.Data["timings"].(map[string]interface{}); ok {
+		fmt.Println("\t" + format(timings))
+	}
+
+	steps, _ := result.Data["steps"].([]interface{})
+	for _, step := range steps {
+		step, ok := step.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		if timings, ok := step["timings"].(map[string]interface{}); ok {
+			fmt.Printf("\tstep %v: %s\n", step["name"], format(timings))
+		}
+	}
+}
+
+func runChangesCommand(ctx context.Context, db *sql.DB, args []string) {
+	fs := flag.NewFlagSet("targets changes", flag.ExitOnError)
+	id := fs.String("id", "", "The ID of the target (can be partial)")
+	limit := fs.Uint("limit", 10, "The number of changes to show")
+	showContent := fs.Bool("content", false, "Show the full content of each snapshot instead of the diff")
+
+	if err := fs.Parse(args); err != nil {
+		logger.Fatalln(err)
+	}
+
+	if *id == "" {
+		fs.Usage()
+		os.Exit(1)
+	}
+
+	target := findTargetByIdPrefix(ctx, db, *id)
+
+	snapshots, err := models.FindContentSnapshotsByTargetId(ctx, db, target.Id(), int(*limit))
+	if err != nil {
+		logger.Fatalln(err)
+	}
+
+	for _, snapshot := range snapshots {
+		logger.Printf("snapshot id=%s hash=%s created_at=%s\n", snapshot.Id(), snapshot.Hash, snapshot.CreatedAt().Format(time.RFC3339))
+
+		if *showContent {
+			fmt.Println(snapshot.Content)
+		} else if snapshot.Diff != "" {
+			fmt.Print(snapshot.Diff)
+		}
+	}
+}
+
+// runTlsCommand prints the TLS inventory from the latest result of each tls
+// target.
+func runTlsCommand(ctx context.Context, db *sql.DB, args []string) {
+	targets, err := models.FindAllTargets(ctx, db)
+	if err != nil {
+		logger.Fatalln(err)
+	}
+
+	for _, target := range targets {
+		if target.Kind != models.TargetKindTls {
+			continue
+		}
+
+		results, err := models.FindCheckResultsByTargetId(ctx, db, target.Id(), 1)
+		if err != nil {
+			logger.Fatalln(err)
+		}
+
+		if len(results) == 0 {
+			logger.Printf("target id=%s name=%q not checked yet\n", target.Id(), target.DisplayName())
+			continue
+		}
+
+		result := results[0]
+		tlsInfo, _ := result.Data["tls"].(map[string]interface{})
+		logger.Printf("target id=%s name=%q status=%s checked_at=%s version=%v cipher=%v issuer=%q not_after=%s\n",
+			target.Id(), target.DisplayName(), result.Status, result.CreatedAt().Format(time.RFC3339),
+			tlsInfo["version"], tlsInfo["cipher"], tlsInfo["issuer"], formatUnix(tlsInfo["not_after"]))
+
+		findings, _ := result.Data["findings"].([]interface{})
+		for _, finding := range findings {
+			if finding, ok := finding.(map[string]interface{}); ok {
+				fmt.Printf("\t%v (%v): %v\n", finding["code"], finding["status"], finding["message"])
+			}
+		}
+	}
+}
+
+func formatUnix(v interface{}) string {
+	unix, ok := v.(float64)
+	if !ok {
+		return ""
+	}
+
+	return time.Unix(int64(unix), 0).UTC().Format(time.RFC3339)
+}
+
+// findTargetByIdPrefix exits if the prefix doesn't match exactly one target.
+func findTargetByIdPrefix(ctx context.Context, db *sql.DB, prefix string) models.Target {
+	targets, err := models.FindTargetsByIdPrefix(ctx, db, prefix)
 	if err != nil {
 		logger.Fatalln(err)
 	}
 
 	if len(targets) == 0 {
-		logger.Fatalln("not found:", id)
+		logger.Fatalln("not found:", prefix)
 	} else if len(targets) != 1 {
-		logger.Fatalln(id, "is ambiguous")
+		logger.Fatalln(prefix, "is ambiguous")
 	}
 
-	target := targets[0]
-	if err := target.Delete(ctx, db); err != nil {
-		logger.Fatalln(err)
+	return targets[0]
+}
+
+// findTargetIdsByIdPrefixes resolves a comma separated list of id prefixes.
+func findTargetIdsByIdPrefixes(ctx context.Context, db *sql.DB, prefixes string) []string {
+	ids := []string{}
+	for _, prefix := range strings.Split(prefixes, ",") {
+		if prefix = strings.TrimSpace(prefix); prefix != "" {
+			target := findTargetByIdPrefix(ctx, db, prefix)
+			ids = append(ids, target.Id())
+		}
 	}
 
-	logger.Println("deleted:", target)
+	return ids
+}
+
+func isFlagSet(fs *flag.FlagSet, name string) bool {
+	set := false
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+
+	return set
+}
+
+func parseConfigFlag(raw string) (map[string]interface{}, error) {
+	var cfg map[string]interface{}
+	if err := json.Unmarshal([]byte(raw), &cfg); err != nil {
+		return nil, fmt.Errorf("invalid config: %v", err)
+	}
+
+	return cfg, nil
+}
+
+func pingUrl(target *models.Target) string {
+	return config.GetBaseUrl() + "/ping/" + target.Id()
 }
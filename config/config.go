@@ -5,17 +5,29 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
 )
 
 const (
 	DefaultPeriod       = 30
 	DefaultResponseCode = 200
 	DefaultMethod       = http.MethodHead
+	DefaultTimeout      = 10
+	DefaultWorkers      = 16
+	// DefaultShutdownTimeout is how many seconds updawg serve waits for
+	// checks in progress to finish when stopping.
+	DefaultShutdownTimeout = 30
+	// DefaultLeaseTtl is how many seconds an updawg serve instance keeps its
+	// targets for without renewing its leases on them.
+	DefaultLeaseTtl = 15
 )
 
 const (
 	DefaultDatabasePath       = "updawg.db"
 	DefaultPubsubDatabasePath = "updawg_pubsub.db"
+	DefaultListenAddr         = "localhost:8080"
 )
 
 var logFile *os.File
@@ -40,7 +52,7 @@ func GetDatabaseUri() string {
 		path = filepath.Join(os.Getenv("PWD"), DefaultDatabasePath)
 	}
 
-	return "file://" + path + "?_pragma=journal_mode(WAL)"
+	return "file://" + path + "?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)"
 }
 
 func GetPubsubDatabaseUri() string {
@@ -49,7 +61,107 @@ func GetPubsubDatabaseUri() string {
 		path = filepath.Join(os.Getenv("PWD"), DefaultPubsubDatabasePath)
 	}
 
-	return "file://" + path + "?_pragma=journal_mode(WAL)"
+	return "file://" + path + "?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)"
+}
+
+func GetListenAddr() string {
+	addr := os.Getenv("UPDAWG_ADDR")
+	if addr == "" {
+		addr = DefaultListenAddr
+	}
+
+	return addr
+}
+
+// GetWorkerCount returns the number of checks updawg serve runs at once.
+func GetWorkerCount() int {
+	workers, err := strconv.Atoi(os.Getenv("UPDAWG_WORKERS"))
+	if err != nil || workers <= 0 {
+		workers = DefaultWorkers
+	}
+
+	return workers
+}
+
+// GetHostConcurrency returns the number of checks updawg serve runs against
+// a single host at once, or zero for no limit.
+func GetHostConcurrency() int {
+	concurrency, err := strconv.Atoi(os.Getenv("UPDAWG_HOST_CONCURRENCY"))
+	if err != nil || concurrency < 0 {
+		return 0
+	}
+
+	return concurrency
+}
+
+// GetRate returns the number of checks updawg serve starts per second, or
+// zero for no limit.
+func GetRate() float64 {
+	return getRateEnv("UPDAWG_RATE")
+}
+
+// GetHostRate returns the number of checks updawg serve starts per second
+// against a single host, or zero for no limit.
+func GetHostRate() float64 {
+	return getRateEnv("UPDAWG_HOST_RATE")
+}
+
+func getRateEnv(name string) float64 {
+	rate, err := strconv.ParseFloat(os.Getenv(name), 64)
+	if err != nil || rate < 0 {
+		return 0
+	}
+
+	return rate
+}
+
+// GetShutdownTimeout returns how long updawg serve waits for checks in
+// progress to finish when stopping.
+func GetShutdownTimeout() time.Duration {
+	timeout, err := time.ParseDuration(os.Getenv("UPDAWG_SHUTDOWN_TIMEOUT"))
+	if err != nil || timeout < 0 {
+		timeout = DefaultShutdownTimeout * time.Second
+	}
+
+	return timeout
+}
+
+// GetLeaseTtl returns how long updawg serve's leases on its targets last
+// without being renewed, after which another instance takes them over.
+//
+// This bounds how long an instance's targets go unchecked if it dies without
+// releasing them: up to the full TTL for its leases to expire, plus up to a
+// third of it until another instance's next renewal claims them. Instances
+// which shut down cleanly release their leases straight away.
+func GetLeaseTtl() time.Duration {
+	ttl, err := time.ParseDuration(os.Getenv("UPDAWG_LEASE_TTL"))
+	if err != nil || ttl <= 0 {
+		ttl = DefaultLeaseTtl * time.Second
+	}
+
+	return ttl
+}
+
+// GetAgentToken returns the token agents authenticate to updawg serve with.
+// Agents can't register while it's unset.
+func GetAgentToken() string {
+	return os.Getenv("UPDAWG_AGENT_TOKEN")
+}
+
+// GetLocation returns the location updawg agent checks targets from.
+func GetLocation() string {
+	return os.Getenv("UPDAWG_LOCATION")
+}
+
+// GetBaseUrl returns the URL updawg serve is reachable at, used when
+// displaying heartbeat ping URLs.
+func GetBaseUrl() string {
+	baseUrl := os.Getenv("UPDAWG_BASE_URL")
+	if baseUrl == "" {
+		baseUrl = "http://" + GetListenAddr()
+	}
+
+	return strings.TrimSuffix(baseUrl, "/")
 }
 
 func GetLogFile() *os.File {
@@ -30,6 +30,13 @@ func Connect(ctx context.Context) *sql.DB {
 	return db
 }
 
+// Close closes db, once nothing is using it.
+func Close(db *sql.DB) {
+	if err := db.Close(); err != nil {
+		logger.Printf("db.Close(): %v", err)
+	}
+}
+
 func setupDatabase(ctx context.Context, db *sql.DB) {
 	_, err := db.ExecContext(ctx, `
 CREATE TABLE IF NOT EXISTS migrations (
@@ -44,26 +51,16 @@ CREATE TABLE IF NOT EXISTS migrations (
 		logger.Fatalf("setupDatabase: %v", err)
 	}
 
-	files, err := migrations.ReadDir("migrations")
+	files, err := getMigrations()
 	if err != nil {
 		logger.Fatalf("setupDatabase: %v", err)
 	}
 
-	for _, file := range files {
-		if file.IsDir() {
-			continue
-		}
-
-		name := file.Name()
-		id, migration, query, err := getMigration(name)
-		if err != nil {
-			logger.Fatalf("setupDatabase: invalid migration %s: %v", name, err)
-		}
-
-		if id > lastMigrationId || firstRun {
-			migrate(ctx, db, id, migration, query)
+	for _, m := range files {
+		if m.id > lastMigrationId || firstRun {
+			migrate(ctx, db, m.id, m.migration, m.query)
 
-			lastMigrationId = id
+			lastMigrationId = m.id
 			firstRun = false
 		}
 	}
@@ -1,10 +1,12 @@
 package database
 
 import (
+	"cmp"
 	"context"
 	"database/sql"
 	"embed"
 	"fmt"
+	"slices"
 	"strconv"
 	"strings"
 )
@@ -53,6 +55,41 @@ func migrate(ctx context.Context, db *sql.DB, id int64, migration, query string)
 	}
 }
 
+type migrationFile struct {
+	id               int64
+	migration, query string
+}
+
+// getMigrations returns every migration in order of their id, which sorting
+// by name doesn't give once ids reach 10.
+func getMigrations() ([]migrationFile, error) {
+	files, err := migrations.ReadDir("migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	var sorted []migrationFile
+	for _, file := range files {
+		if file.IsDir() {
+			continue
+		}
+
+		name := file.Name()
+		id, migration, query, err := getMigration(name)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration %s: %v", name, err)
+		}
+
+		sorted = append(sorted, migrationFile{id, migration, query})
+	}
+
+	slices.SortFunc(sorted, func(a, b migrationFile) int {
+		return cmp.Compare(a.id, b.id)
+	})
+
+	return sorted, nil
+}
+
 func getMigration(name string) (id int64, migration string, query string, err error) {
 	fail := func(err error) (int64, string, string, error) {
 		return 0, "", "", err
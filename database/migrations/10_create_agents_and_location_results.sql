@@ -0,0 +1,23 @@
+CREATE TABLE agents (
+  pk integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+  id uuid NOT NULL,
+  location varchar(255) NOT NULL,
+  hostname varchar(255) NOT NULL,
+  last_seen_at integer NOT NULL,
+  created_at integer NOT NULL
+);
+
+CREATE UNIQUE INDEX agents_on_id ON agents (id);
+CREATE UNIQUE INDEX agents_on_location_and_hostname ON agents (location, hostname);
+
+CREATE TABLE location_results (
+  target_id uuid NOT NULL,
+  location varchar(255) NOT NULL,
+  agent_id uuid NOT NULL,
+  status varchar(16) NOT NULL,
+  message text,
+  duration integer NOT NULL DEFAULT 0,
+  data json NOT NULL DEFAULT '{}',
+  checked_at integer NOT NULL,
+  PRIMARY KEY (target_id, location)
+);
@@ -0,0 +1 @@
+UPDATE targets SET config = json_set(config, '$.dsn', uri), uri = '' WHERE kind = 'sql' AND uri != '';
@@ -0,0 +1,2 @@
+ALTER TABLE targets
+ADD COLUMN kind varchar(32) NOT NULL DEFAULT 'http';
@@ -0,0 +1,13 @@
+CREATE TABLE check_results (
+  pk integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+  id uuid NOT NULL,
+  target_id uuid NOT NULL,
+  status varchar(16) NOT NULL,
+  message text,
+  duration integer NOT NULL DEFAULT 0,
+  data json NOT NULL DEFAULT '{}',
+  created_at integer NOT NULL
+);
+
+CREATE UNIQUE INDEX check_results_on_id ON check_results (id);
+CREATE INDEX check_results_on_target_id ON check_results (target_id);
@@ -0,0 +1,12 @@
+CREATE TABLE content_snapshots (
+  pk integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+  id uuid NOT NULL,
+  target_id uuid NOT NULL,
+  hash varchar(64) NOT NULL,
+  content text NOT NULL,
+  diff text NOT NULL DEFAULT '',
+  created_at integer NOT NULL
+);
+
+CREATE UNIQUE INDEX content_snapshots_on_id ON content_snapshots (id);
+CREATE INDEX content_snapshots_on_target_id ON content_snapshots (target_id);
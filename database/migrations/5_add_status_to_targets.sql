@@ -0,0 +1,5 @@
+ALTER TABLE targets
+ADD COLUMN status varchar(16) NOT NULL DEFAULT 'unknown';
+
+ALTER TABLE targets
+ADD COLUMN status_changed_at integer;
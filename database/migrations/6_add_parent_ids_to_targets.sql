@@ -0,0 +1,2 @@
+ALTER TABLE targets
+ADD COLUMN parent_ids json NOT NULL DEFAULT '[]';
@@ -0,0 +1,8 @@
+ALTER TABLE targets
+ADD COLUMN schedule varchar(255) NOT NULL DEFAULT '';
+
+ALTER TABLE targets
+ADD COLUMN timezone varchar(64) NOT NULL DEFAULT '';
+
+ALTER TABLE targets
+ADD COLUMN active_hours varchar(255) NOT NULL DEFAULT '';
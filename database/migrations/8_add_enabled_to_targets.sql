@@ -0,0 +1,5 @@
+ALTER TABLE targets
+ADD COLUMN enabled boolean NOT NULL DEFAULT 1;
+
+ALTER TABLE targets
+ADD COLUMN paused_until integer;
@@ -0,0 +1,18 @@
+CREATE TABLE instances (
+  pk integer NOT NULL PRIMARY KEY AUTOINCREMENT,
+  id uuid NOT NULL,
+  hostname varchar(255) NOT NULL,
+  pid integer NOT NULL,
+  heartbeat_at integer NOT NULL,
+  created_at integer NOT NULL
+);
+
+CREATE UNIQUE INDEX instances_on_id ON instances (id);
+
+CREATE TABLE target_leases (
+  target_id uuid NOT NULL PRIMARY KEY,
+  instance_id uuid NOT NULL,
+  expires_at integer NOT NULL
+);
+
+CREATE INDEX target_leases_on_instance_id ON target_leases (instance_id);
@@ -0,0 +1,79 @@
+package database
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+)
+
+func TestGetMigrationsOrder(t *testing.T) {
+	files, err := getMigrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(files) < 11 {
+		t.Fatalf("got %d migrations, want at least 11 so ids of two digits are covered", len(files))
+	}
+
+	for i, m := range files {
+		if m.id != int64(i) {
+			t.Fatalf("migration %d is %d_%s, want migrations ordered by id without gaps", i, m.id, m.migration)
+		}
+	}
+}
+
+func TestGetMigration(t *testing.T) {
+	tests := []struct {
+		name          string
+		wantId        int64
+		wantMigration string
+		wantErr       bool
+	}{
+		{name: "0_create_targets.sql", wantId: 0, wantMigration: "create_targets.sql"},
+		{name: "10_create_agents_and_location_results.sql", wantId: 10, wantMigration: "create_agents_and_location_results.sql"},
+		{name: "create_targets.sql", wantErr: true},
+		{name: "x_create_targets.sql", wantErr: true},
+		{name: "-1_create_targets.sql", wantErr: true},
+		{name: "99_missing.sql", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, migration, _, err := getMigration(tt.name)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("getMigration() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if id != tt.wantId || migration != tt.wantMigration {
+				t.Errorf("getMigration() = %d, %q, want %d, %q", id, migration, tt.wantId, tt.wantMigration)
+			}
+		})
+	}
+}
+
+func TestConnectMigrates(t *testing.T) {
+	t.Setenv("UPDAWG_DB", filepath.Join(t.TempDir(), "test.db"))
+	ctx := context.Background()
+
+	files, err := getMigrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Connecting again must not re-run any migration
+	for i := 0; i < 2; i++ {
+		db := Connect(ctx)
+
+		var count, last int64
+		if err := db.QueryRowContext(ctx, "SELECT count(*), max(id) FROM migrations").Scan(&count, &last); err != nil {
+			t.Fatal(err)
+		}
+
+		if count != int64(len(files)) || last != files[len(files)-1].id {
+			t.Errorf("connection %d: ran %d migrations up to %d, want %d up to %d", i, count, last, len(files), files[len(files)-1].id)
+		}
+
+		Close(db)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"os"
 	"os/signal"
+	"syscall"
 
 	"github.com/tehlordvortex/updawg/cli"
 	_ "github.com/tehlordvortex/updawg/config"
@@ -20,14 +21,23 @@ func main() {
 
 	go func() {
 		sigChan := make(chan os.Signal, 1)
-		signal.Notify(sigChan, os.Interrupt)
+		signal.Notify(sigChan, os.Interrupt, syscall.SIGTERM)
 
 		select {
 		case <-sigChan:
 			cancel()
 		case <-ctx.Done():
+			return
 		}
+
+		// A second signal exits without waiting for shutdown to finish
+		<-sigChan
+		os.Exit(1)
 	}()
 
 	cli.Run(ctx, db)
+
+	cancel()
+	pubsub.Close()
+	database.Close(db)
 }
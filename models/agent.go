@@ -0,0 +1,139 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+const AgentModelTableName = "agents"
+
+// Agent is an updawg agent process which checks targets from its location
+// and reports the results to updawg serve.
+type Agent struct {
+	pk         int64
+	id         string
+	Location   string
+	Hostname   string
+	lastSeenAt time.Time
+	createdAt  time.Time
+}
+
+func (a *Agent) Pk() int64             { return a.pk }
+func (a *Agent) Id() string            { return a.id }
+func (a *Agent) LastSeenAt() time.Time { return a.lastSeenAt }
+func (a *Agent) CreatedAt() time.Time  { return a.createdAt }
+
+// Touch records that the agent has just contacted the server.
+func (a *Agent) Touch(ctx context.Context, qe QueryExecutor) error {
+	if a.pk == -1 {
+		return ErrRecordDeleted
+	} else if a.pk == 0 && a.id == "" {
+		return ErrRecordNotPersisted
+	}
+
+	unix := time.Now().UTC().Unix()
+
+	_, err := qe.ExecContext(ctx, "UPDATE agents SET last_seen_at = ? WHERE pk = ?", unix, a.pk)
+	if err != nil {
+		return fmt.Errorf("agent.Touch(%s): %v", a.id, err)
+	}
+
+	a.lastSeenAt = time.Unix(unix, 0)
+	return nil
+}
+
+// Agent impl PassiveRecord
+
+func (a *Agent) Load(Scan PassiveRecordScanFunc) error {
+	return loadAgent(a, Scan)
+}
+
+func (a *Agent) Reload(ctx context.Context, qe QueryExecutor) error {
+	if a.pk == -1 {
+		return ErrRecordDeleted
+	} else if a.pk == 0 && a.id == "" {
+		return ErrRecordNotPersisted
+	}
+
+	row := qe.QueryRowContext(ctx, "SELECT * FROM agents WHERE pk = ?", a.pk)
+
+	return a.Load(func(cols []interface{}) error {
+		return row.Scan(cols...)
+	})
+}
+
+// Save registers the agent. An agent which registers again from the same
+// location and hostname, such as after restarting, gets its existing record
+// back rather than a new one. Agents cannot be modified once saved, other
+// than by Touch.
+func (a *Agent) Save(ctx context.Context, qe QueryExecutor) error {
+	if a.pk != 0 || a.id != "" {
+		return fmt.Errorf("agent.Save(%s): agents cannot be modified", a.id)
+	}
+
+	if a.Location == "" {
+		return fmt.Errorf("agent must have a location")
+	}
+
+	unix := time.Now().UTC().Unix()
+
+	row := qe.QueryRowContext(ctx, `
+INSERT INTO agents (id, location, hostname, last_seen_at, created_at) VALUES (?, ?, ?, ?, ?)
+ON CONFLICT (location, hostname) DO UPDATE SET last_seen_at = excluded.last_seen_at
+RETURNING *`, GenUlid("agent"), a.Location, a.Hostname, unix, unix)
+
+	if err := a.Load(func(cols []interface{}) error {
+		return row.Scan(cols...)
+	}); err != nil {
+		return fmt.Errorf("agent.Save: %v", err)
+	}
+
+	return nil
+}
+
+func (a *Agent) Delete(ctx context.Context, qe QueryExecutor) error {
+	if a.pk == -1 {
+		return ErrRecordDeleted
+	}
+
+	_, err := qe.ExecContext(ctx, "DELETE FROM agents WHERE pk = ?", a.pk)
+	if err != nil {
+		return err
+	}
+
+	a.pk = -1
+	return nil
+}
+
+func FindAgentById(ctx context.Context, qe QueryExecutor, id string) (Agent, error) {
+	return LoadAgent(qe.QueryRowContext(ctx, "SELECT * FROM agents WHERE id = ?", id))
+}
+
+func LoadAgent(row *sql.Row) (Agent, error) {
+	var a Agent
+
+	if err := a.Load(func(cols []interface{}) error {
+		return row.Scan(cols...)
+	}); err != nil {
+		return Agent{}, fmt.Errorf("LoadAgent: %w", err)
+	}
+
+	return a, nil
+}
+
+func loadAgent(a *Agent, Scan PassiveRecordScanFunc) error {
+	var lastSeenAtUnix, createdAtUnix int64
+
+	cols := []interface{}{&a.pk, &a.id, &a.Location, &a.Hostname, &lastSeenAtUnix, &createdAtUnix}
+	err := Scan(cols)
+	if err != nil {
+		return err
+	}
+
+	a.lastSeenAt = time.Unix(lastSeenAtUnix, 0)
+	a.createdAt = time.Unix(createdAtUnix, 0)
+
+	return nil
+}
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+
+	"github.com/tehlordvortex/updawg/pubsub"
+)
+
+const (
+	TargetCheckRequestedTopic = "target.check_requested"
+)
+
+// CheckRequest asks the targets worker to check a target straight away. The
+// resulting check result lists the request's id under "request_ids" in its
+// data so the requester can find it.
+type CheckRequest struct {
+	Id       string `json:"id"`
+	TargetId string `json:"target_id"`
+}
+
+// PublishCheckRequest publishes a request to check the target, returning the
+// request with its id set.
+func PublishCheckRequest(ctx context.Context, targetId string) (CheckRequest, error) {
+	request := CheckRequest{Id: GenUlid("checkreq"), TargetId: targetId}
+
+	msg, err := json.Marshal(request)
+	if err != nil {
+		return request, err
+	}
+
+	return request, pubsub.Publish(ctx, TargetCheckRequestedTopic, string(msg))
+}
+
+func DecodeCheckRequest(msg string) (CheckRequest, error) {
+	var request CheckRequest
+	err := json.Unmarshal([]byte(msg), &request)
+
+	return request, err
+}
+
+// IsResultFor reports whether result was produced in answer to the request.
+func (r CheckRequest) IsResultFor(result *CheckResult) bool {
+	if result.TargetId != r.TargetId {
+		return false
+	}
+
+	switch ids := result.Data["request_ids"].(type) {
+	case []string:
+		return slices.Contains(ids, r.Id)
+	case []interface{}:
+		return slices.Contains(ids, interface{}(r.Id))
+	default:
+		return false
+	}
+}
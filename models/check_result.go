@@ -0,0 +1,220 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/tehlordvortex/updawg/pubsub"
+)
+
+const (
+	CheckResultModelTableName = "check_results"
+	CheckResultCreatedTopic   = "check_result.created"
+)
+
+type Status string
+
+const (
+	StatusUp       Status = "up"
+	StatusDegraded Status = "degraded"
+	StatusDown     Status = "down"
+	StatusUnknown  Status = "unknown"
+	// StatusUnreachable replaces down while one of the target's parents is
+	// down.
+	StatusUnreachable Status = "unreachable"
+	// StatusPartial is used when some, but not all, of a target's addresses
+	// are down.
+	StatusPartial Status = "partial"
+)
+
+// IsFailure reports whether the status should be alerted on. Unreachable
+// targets are not failures of their own.
+func (s Status) IsFailure() bool {
+	return s == StatusDown || s == StatusDegraded || s == StatusPartial
+}
+
+type CheckResult struct {
+	pk        int64
+	id        string
+	TargetId  string
+	Status    Status
+	Message   string
+	Duration  time.Duration
+	Data      map[string]interface{}
+	createdAt time.Time
+}
+
+func (r *CheckResult) Pk() int64            { return r.pk }
+func (r *CheckResult) Id() string           { return r.id }
+func (r *CheckResult) CreatedAt() time.Time { return r.createdAt }
+
+func (r *CheckResult) String() string {
+	return fmt.Sprintf("%s target=%s status=%s duration=%s message=%q", r.createdAt.Format(time.RFC3339), r.TargetId, r.Status, r.Duration, r.Message)
+}
+
+// CheckResult impl PassiveRecord
+
+func (r *CheckResult) Load(Scan PassiveRecordScanFunc) error {
+	return loadCheckResult(r, Scan)
+}
+
+func (r *CheckResult) Reload(ctx context.Context, qe QueryExecutor) error {
+	if r.pk == -1 {
+		return ErrRecordDeleted
+	} else if r.pk == 0 && r.id == "" {
+		return ErrRecordNotPersisted
+	}
+
+	row := qe.QueryRowContext(ctx, "SELECT * FROM check_results WHERE pk = ?", r.pk)
+
+	return r.Load(func(cols []interface{}) error {
+		return row.Scan(cols...)
+	})
+}
+
+// Save inserts the check result. Check results are immutable once saved.
+func (r *CheckResult) Save(ctx context.Context, qe QueryExecutor) error {
+	if r.pk != 0 || r.id != "" {
+		return fmt.Errorf("checkResult.Save(%s): check results cannot be modified", r.id)
+	}
+
+	if r.TargetId == "" {
+		return fmt.Errorf("check result must have a target")
+	}
+
+	if r.Status == "" {
+		r.Status = StatusUnknown
+	}
+
+	if r.Data == nil {
+		r.Data = make(map[string]interface{})
+	}
+
+	dataJson, err := json.Marshal(r.Data)
+	if err != nil {
+		return fmt.Errorf("checkResult.Save: %v", err)
+	}
+
+	unix := time.Now().UTC().Unix()
+	id := GenUlid("result")
+
+	result, err := qe.ExecContext(ctx, "INSERT INTO check_results (id, target_id, status, message, duration, data, created_at) VALUES (?, ?, ?, ?, ?, ?, ?)", id, r.TargetId, r.Status, r.Message, int64(r.Duration), string(dataJson), unix)
+	if err != nil {
+		return fmt.Errorf("checkResult.Save: %v", err)
+	}
+
+	pk, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("checkResult.Save: %v", err)
+	}
+
+	r.pk = pk
+	r.id = id
+	r.createdAt = time.Unix(unix, 0)
+
+	_ = pubsub.Publish(ctx, CheckResultCreatedTopic, r.id)
+	return nil
+}
+
+func (r *CheckResult) Delete(ctx context.Context, qe QueryExecutor) error {
+	if r.pk == -1 {
+		return ErrRecordDeleted
+	}
+
+	_, err := qe.ExecContext(ctx, "DELETE FROM check_results WHERE pk = ?", r.pk)
+	if err != nil {
+		return err
+	}
+
+	r.pk = -1
+	return nil
+}
+
+func FindCheckResultById(ctx context.Context, qe QueryExecutor, id string) (CheckResult, error) {
+	return LoadCheckResult(qe.QueryRowContext(ctx, "SELECT * FROM check_results WHERE id = ?", id))
+}
+
+// FindCheckResultsByTargetId returns the most recent check results for a
+// target, newest first.
+func FindCheckResultsByTargetId(ctx context.Context, qe QueryExecutor, targetId string, limit int) ([]CheckResult, error) {
+	rows, err := qe.QueryContext(ctx, "SELECT * FROM check_results WHERE target_id = ? ORDER BY pk DESC LIMIT ?", targetId, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return LoadCheckResults(rows)
+}
+
+// FindCheckResultsByTargetIdSince returns a target's check results created at
+// or after since, oldest first.
+func FindCheckResultsByTargetIdSince(ctx context.Context, qe QueryExecutor, targetId string, since time.Time) ([]CheckResult, error) {
+	rows, err := qe.QueryContext(ctx, "SELECT * FROM check_results WHERE target_id = ? AND created_at >= ? ORDER BY pk", targetId, since.UTC().Unix())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return LoadCheckResults(rows)
+}
+
+func LoadCheckResult(row *sql.Row) (CheckResult, error) {
+	var r CheckResult
+
+	if err := r.Load(func(cols []interface{}) error {
+		return row.Scan(cols...)
+	}); err != nil {
+		return CheckResult{}, fmt.Errorf("LoadCheckResult: %v", err)
+	}
+
+	return r, nil
+}
+
+func LoadCheckResults(rows *sql.Rows) ([]CheckResult, error) {
+	var results []CheckResult
+
+	for rows.Next() {
+		var r CheckResult
+
+		err := r.Load(func(cols []interface{}) error {
+			return rows.Scan(cols...)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("LoadCheckResults: %v", err)
+		}
+
+		results = append(results, r)
+	}
+
+	return results, nil
+}
+
+func loadCheckResult(r *CheckResult, Scan PassiveRecordScanFunc) error {
+	var messageNullable sql.NullString
+	var dataJson string
+	var durationNs, createdAtUnix int64
+
+	cols := []interface{}{&r.pk, &r.id, &r.TargetId, &r.Status, &messageNullable, &durationNs, &dataJson, &createdAtUnix}
+	err := Scan(cols)
+	if err != nil {
+		return err
+	}
+
+	if messageNullable.Valid {
+		r.Message = messageNullable.String
+	}
+
+	r.Data = nil
+	err = json.Unmarshal([]byte(dataJson), &r.Data)
+	if err != nil {
+		return err
+	}
+
+	r.Duration = time.Duration(durationNs)
+	r.createdAt = time.Unix(createdAtUnix, 0)
+
+	return nil
+}
@@ -0,0 +1,162 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/tehlordvortex/updawg/pubsub"
+)
+
+const (
+	ContentSnapshotModelTableName = "content_snapshots"
+	TargetContentChangedTopic     = "target.content_changed"
+)
+
+// ContentSnapshot is the normalized content of a content target, stored each
+// time it changes. Diff is empty for the first snapshot of a target.
+type ContentSnapshot struct {
+	pk        int64
+	id        string
+	TargetId  string
+	Hash      string
+	Content   string
+	Diff      string
+	createdAt time.Time
+}
+
+func (s *ContentSnapshot) Pk() int64            { return s.pk }
+func (s *ContentSnapshot) Id() string           { return s.id }
+func (s *ContentSnapshot) CreatedAt() time.Time { return s.createdAt }
+
+// ContentSnapshot impl PassiveRecord
+
+func (s *ContentSnapshot) Load(Scan PassiveRecordScanFunc) error {
+	return loadContentSnapshot(s, Scan)
+}
+
+func (s *ContentSnapshot) Reload(ctx context.Context, qe QueryExecutor) error {
+	if s.pk == -1 {
+		return ErrRecordDeleted
+	} else if s.pk == 0 && s.id == "" {
+		return ErrRecordNotPersisted
+	}
+
+	row := qe.QueryRowContext(ctx, "SELECT * FROM content_snapshots WHERE pk = ?", s.pk)
+
+	return s.Load(func(cols []interface{}) error {
+		return row.Scan(cols...)
+	})
+}
+
+// Save inserts the snapshot and, if it has a diff, publishes
+// TargetContentChangedTopic with the snapshot's id. Snapshots are immutable
+// once saved.
+func (s *ContentSnapshot) Save(ctx context.Context, qe QueryExecutor) error {
+	if s.pk != 0 || s.id != "" {
+		return fmt.Errorf("contentSnapshot.Save(%s): content snapshots cannot be modified", s.id)
+	}
+
+	if s.TargetId == "" {
+		return fmt.Errorf("content snapshot must have a target")
+	}
+
+	unix := time.Now().UTC().Unix()
+	id := GenUlid("snapshot")
+
+	result, err := qe.ExecContext(ctx, "INSERT INTO content_snapshots (id, target_id, hash, content, diff, created_at) VALUES (?, ?, ?, ?, ?, ?)", id, s.TargetId, s.Hash, s.Content, s.Diff, unix)
+	if err != nil {
+		return fmt.Errorf("contentSnapshot.Save: %v", err)
+	}
+
+	pk, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("contentSnapshot.Save: %v", err)
+	}
+
+	s.pk = pk
+	s.id = id
+	s.createdAt = time.Unix(unix, 0)
+
+	if s.Diff != "" {
+		_ = pubsub.Publish(ctx, TargetContentChangedTopic, s.id)
+	}
+
+	return nil
+}
+
+func (s *ContentSnapshot) Delete(ctx context.Context, qe QueryExecutor) error {
+	if s.pk == -1 {
+		return ErrRecordDeleted
+	}
+
+	_, err := qe.ExecContext(ctx, "DELETE FROM content_snapshots WHERE pk = ?", s.pk)
+	if err != nil {
+		return err
+	}
+
+	s.pk = -1
+	return nil
+}
+
+func FindLatestContentSnapshotByTargetId(ctx context.Context, qe QueryExecutor, targetId string) (ContentSnapshot, error) {
+	return LoadContentSnapshot(qe.QueryRowContext(ctx, "SELECT * FROM content_snapshots WHERE target_id = ? ORDER BY pk DESC LIMIT 1", targetId))
+}
+
+// FindContentSnapshotsByTargetId returns the most recent snapshots for a
+// target, newest first.
+func FindContentSnapshotsByTargetId(ctx context.Context, qe QueryExecutor, targetId string, limit int) ([]ContentSnapshot, error) {
+	rows, err := qe.QueryContext(ctx, "SELECT * FROM content_snapshots WHERE target_id = ? ORDER BY pk DESC LIMIT ?", targetId, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return LoadContentSnapshots(rows)
+}
+
+func LoadContentSnapshot(row *sql.Row) (ContentSnapshot, error) {
+	var s ContentSnapshot
+
+	if err := s.Load(func(cols []interface{}) error {
+		return row.Scan(cols...)
+	}); err != nil {
+		return ContentSnapshot{}, fmt.Errorf("LoadContentSnapshot: %w", err)
+	}
+
+	return s, nil
+}
+
+func LoadContentSnapshots(rows *sql.Rows) ([]ContentSnapshot, error) {
+	var snapshots []ContentSnapshot
+
+	for rows.Next() {
+		var s ContentSnapshot
+
+		err := s.Load(func(cols []interface{}) error {
+			return rows.Scan(cols...)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("LoadContentSnapshots: %v", err)
+		}
+
+		snapshots = append(snapshots, s)
+	}
+
+	return snapshots, nil
+}
+
+func loadContentSnapshot(s *ContentSnapshot, Scan PassiveRecordScanFunc) error {
+	var createdAtUnix int64
+
+	cols := []interface{}{&s.pk, &s.id, &s.TargetId, &s.Hash, &s.Content, &s.Diff, &createdAtUnix}
+	err := Scan(cols)
+	if err != nil {
+		return err
+	}
+
+	s.createdAt = time.Unix(createdAtUnix, 0)
+
+	return nil
+}
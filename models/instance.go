@@ -0,0 +1,204 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/tehlordvortex/updawg/pubsub"
+)
+
+const (
+	InstanceModelTableName = "instances"
+	// InstanceJoinedTopic and InstanceLeftTopic are published with the
+	// instance's id when updawg serve starts and stops, so other instances
+	// rebalance targets straight away.
+	InstanceJoinedTopic = "instance.joined"
+	InstanceLeftTopic   = "instance.left"
+)
+
+// Instance is a running updawg serve process. Instances heartbeat while
+// they're running and are considered dead once they stop.
+type Instance struct {
+	pk          int64
+	id          string
+	Hostname    string
+	Pid         int
+	heartbeatAt time.Time
+	createdAt   time.Time
+}
+
+func (i *Instance) Pk() int64              { return i.pk }
+func (i *Instance) Id() string             { return i.id }
+func (i *Instance) HeartbeatAt() time.Time { return i.heartbeatAt }
+func (i *Instance) CreatedAt() time.Time   { return i.createdAt }
+
+// Heartbeat records that the instance is still running, registering it
+// again if another instance deleted it after it missed its heartbeats.
+func (i *Instance) Heartbeat(ctx context.Context, qe QueryExecutor) error {
+	if i.pk == -1 {
+		return ErrRecordDeleted
+	} else if i.pk == 0 && i.id == "" {
+		return ErrRecordNotPersisted
+	}
+
+	unix := time.Now().UTC().Unix()
+
+	row := qe.QueryRowContext(ctx, `
+INSERT INTO instances (id, hostname, pid, heartbeat_at, created_at) VALUES (?, ?, ?, ?, ?)
+ON CONFLICT (id) DO UPDATE SET heartbeat_at = excluded.heartbeat_at
+RETURNING pk`, i.id, i.Hostname, i.Pid, unix, i.createdAt.UTC().Unix())
+	if err := row.Scan(&i.pk); err != nil {
+		return fmt.Errorf("instance.Heartbeat(%s): %v", i.id, err)
+	}
+
+	i.heartbeatAt = time.Unix(unix, 0)
+	return nil
+}
+
+// Instance impl PassiveRecord
+
+func (i *Instance) Load(Scan PassiveRecordScanFunc) error {
+	return loadInstance(i, Scan)
+}
+
+func (i *Instance) Reload(ctx context.Context, qe QueryExecutor) error {
+	if i.pk == -1 {
+		return ErrRecordDeleted
+	} else if i.pk == 0 && i.id == "" {
+		return ErrRecordNotPersisted
+	}
+
+	row := qe.QueryRowContext(ctx, "SELECT * FROM instances WHERE pk = ?", i.pk)
+
+	return i.Load(func(cols []interface{}) error {
+		return row.Scan(cols...)
+	})
+}
+
+// Save inserts the instance and publishes InstanceJoinedTopic. Instances
+// cannot be modified once saved, other than by Heartbeat.
+func (i *Instance) Save(ctx context.Context, qe QueryExecutor) error {
+	if i.pk != 0 || i.id != "" {
+		return fmt.Errorf("instance.Save(%s): instances cannot be modified", i.id)
+	}
+
+	unix := time.Now().UTC().Unix()
+	id := GenUlid("instance")
+
+	result, err := qe.ExecContext(ctx, "INSERT INTO instances (id, hostname, pid, heartbeat_at, created_at) VALUES (?, ?, ?, ?, ?)", id, i.Hostname, i.Pid, unix, unix)
+	if err != nil {
+		return fmt.Errorf("instance.Save: %v", err)
+	}
+
+	pk, err := result.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("instance.Save: %v", err)
+	}
+
+	i.pk = pk
+	i.id = id
+	i.heartbeatAt = time.Unix(unix, 0)
+	i.createdAt = i.heartbeatAt
+
+	_ = pubsub.Publish(ctx, InstanceJoinedTopic, i.id)
+	return nil
+}
+
+// Delete removes the instance along with its target leases and publishes
+// InstanceLeftTopic, so other instances take over its targets.
+func (i *Instance) Delete(ctx context.Context, qe QueryExecutor) error {
+	if i.pk == -1 {
+		return ErrRecordDeleted
+	}
+
+	if err := ReleaseTargetLeases(ctx, qe, i.id); err != nil {
+		return err
+	}
+
+	_, err := qe.ExecContext(ctx, "DELETE FROM instances WHERE pk = ?", i.pk)
+	if err != nil {
+		return err
+	}
+
+	i.pk = -1
+
+	_ = pubsub.Publish(ctx, InstanceLeftTopic, i.id)
+	return nil
+}
+
+// FindLiveInstances returns the instances which have heartbeat since the
+// given time, oldest first.
+func FindLiveInstances(ctx context.Context, qe QueryExecutor, since time.Time) ([]Instance, error) {
+	rows, err := qe.QueryContext(ctx, "SELECT * FROM instances WHERE heartbeat_at >= ? ORDER BY pk", since.UTC().Unix())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return LoadInstances(rows)
+}
+
+// DeleteDeadInstances deletes the instances which haven't heartbeat since
+// the given time, returning how many were deleted. Their leases are kept, so
+// they can still be taken over once they expire.
+func DeleteDeadInstances(ctx context.Context, qe QueryExecutor, since time.Time) (int64, error) {
+	result, err := qe.ExecContext(ctx, "DELETE FROM instances WHERE heartbeat_at < ?", since.UTC().Unix())
+	if err != nil {
+		return 0, fmt.Errorf("DeleteDeadInstances: %v", err)
+	}
+
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("DeleteDeadInstances: %v", err)
+	}
+
+	return deleted, nil
+}
+
+func LoadInstance(row *sql.Row) (Instance, error) {
+	var i Instance
+
+	if err := i.Load(func(cols []interface{}) error {
+		return row.Scan(cols...)
+	}); err != nil {
+		return Instance{}, fmt.Errorf("LoadInstance: %w", err)
+	}
+
+	return i, nil
+}
+
+func LoadInstances(rows *sql.Rows) ([]Instance, error) {
+	var instances []Instance
+
+	for rows.Next() {
+		var i Instance
+
+		err := i.Load(func(cols []interface{}) error {
+			return rows.Scan(cols...)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("LoadInstances: %v", err)
+		}
+
+		instances = append(instances, i)
+	}
+
+	return instances, nil
+}
+
+func loadInstance(i *Instance, Scan PassiveRecordScanFunc) error {
+	var heartbeatAtUnix, createdAtUnix int64
+
+	cols := []interface{}{&i.pk, &i.id, &i.Hostname, &i.Pid, &heartbeatAtUnix, &createdAtUnix}
+	err := Scan(cols)
+	if err != nil {
+		return err
+	}
+
+	i.heartbeatAt = time.Unix(heartbeatAtUnix, 0)
+	i.createdAt = time.Unix(createdAtUnix, 0)
+
+	return nil
+}
@@ -0,0 +1,132 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+const LocationResultModelTableName = "location_results"
+
+// LocationResult is the latest result an agent reported for a target from
+// its location. Each location keeps only its latest result, which the server
+// combines into the target's own check results.
+type LocationResult struct {
+	TargetId  string
+	Location  string
+	AgentId   string
+	Status    Status
+	Message   string
+	Duration  time.Duration
+	Data      map[string]interface{}
+	checkedAt time.Time
+}
+
+// CheckedAt is when the server received the result.
+func (r *LocationResult) CheckedAt() time.Time { return r.checkedAt }
+
+// Save replaces the location's previous result for the target.
+func (r *LocationResult) Save(ctx context.Context, qe QueryExecutor) error {
+	if r.TargetId == "" || r.Location == "" {
+		return fmt.Errorf("location result must have a target and location")
+	}
+
+	if r.Status == "" {
+		r.Status = StatusUnknown
+	}
+
+	if r.Data == nil {
+		r.Data = make(map[string]interface{})
+	}
+
+	dataJson, err := json.Marshal(r.Data)
+	if err != nil {
+		return fmt.Errorf("locationResult.Save: %v", err)
+	}
+
+	unix := time.Now().UTC().Unix()
+
+	_, err = qe.ExecContext(ctx, `
+INSERT INTO location_results (target_id, location, agent_id, status, message, duration, data, checked_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+ON CONFLICT (target_id, location) DO UPDATE SET (agent_id, status, message, duration, data, checked_at) = (excluded.agent_id, excluded.status, excluded.message, excluded.duration, excluded.data, excluded.checked_at)`,
+		r.TargetId, r.Location, r.AgentId, r.Status, r.Message, int64(r.Duration), string(dataJson), unix)
+	if err != nil {
+		return fmt.Errorf("locationResult.Save: %v", err)
+	}
+
+	r.checkedAt = time.Unix(unix, 0)
+
+	return nil
+}
+
+func FindLocationResult(ctx context.Context, qe QueryExecutor, targetId, location string) (LocationResult, error) {
+	return LoadLocationResult(qe.QueryRowContext(ctx, "SELECT * FROM location_results WHERE target_id = ? AND location = ?", targetId, location))
+}
+
+func FindLocationResultsByTargetId(ctx context.Context, qe QueryExecutor, targetId string) ([]LocationResult, error) {
+	rows, err := qe.QueryContext(ctx, "SELECT * FROM location_results WHERE target_id = ? ORDER BY location", targetId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return LoadLocationResults(rows)
+}
+
+func LoadLocationResult(row *sql.Row) (LocationResult, error) {
+	var r LocationResult
+
+	if err := loadLocationResult(&r, func(cols []interface{}) error {
+		return row.Scan(cols...)
+	}); err != nil {
+		return LocationResult{}, fmt.Errorf("LoadLocationResult: %w", err)
+	}
+
+	return r, nil
+}
+
+func LoadLocationResults(rows *sql.Rows) ([]LocationResult, error) {
+	var results []LocationResult
+
+	for rows.Next() {
+		var r LocationResult
+
+		err := loadLocationResult(&r, func(cols []interface{}) error {
+			return rows.Scan(cols...)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("LoadLocationResults: %v", err)
+		}
+
+		results = append(results, r)
+	}
+
+	return results, nil
+}
+
+func loadLocationResult(r *LocationResult, Scan PassiveRecordScanFunc) error {
+	var messageNullable sql.NullString
+	var dataJson string
+	var durationNs, checkedAtUnix int64
+
+	cols := []interface{}{&r.TargetId, &r.Location, &r.AgentId, &r.Status, &messageNullable, &durationNs, &dataJson, &checkedAtUnix}
+	err := Scan(cols)
+	if err != nil {
+		return err
+	}
+
+	r.Message = messageNullable.String
+
+	r.Data = nil
+	err = json.Unmarshal([]byte(dataJson), &r.Data)
+	if err != nil {
+		return err
+	}
+
+	r.Duration = time.Duration(durationNs)
+	r.checkedAt = time.Unix(checkedAtUnix, 0)
+
+	return nil
+}
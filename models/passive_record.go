@@ -27,3 +27,19 @@ type PassiveRecord interface {
 	Save(context.Context, QueryExecutor) error
 	Delete(context.Context, QueryExecutor) error
 }
+
+// loadIds scans rows with a single id column.
+func loadIds(rows *sql.Rows) ([]string, error) {
+	var ids []string
+
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("loadIds: %v", err)
+		}
+
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"time"
+
+	"github.com/tehlordvortex/updawg/pubsub"
+)
+
+const (
+	TargetPingedTopic = "target.pinged"
+)
+
+const (
+	PingSignalStart   = "start"
+	PingSignalSuccess = "success"
+	PingSignalFail    = "fail"
+)
+
+var PingSignals = []string{PingSignalStart, PingSignalSuccess, PingSignalFail}
+
+// Ping is a heartbeat received for a heartbeat target. Pings are not stored,
+// they are relayed to the targets worker over pubsub which records the
+// resulting check results.
+type Ping struct {
+	TargetId string        `json:"target_id"`
+	Signal   string        `json:"signal"`
+	Duration time.Duration `json:"duration,omitempty"`
+	Message  string        `json:"message,omitempty"`
+}
+
+func IsPingSignal(signal string) bool {
+	return slices.Contains(PingSignals, signal)
+}
+
+func PublishPing(ctx context.Context, ping Ping) error {
+	msg, err := json.Marshal(ping)
+	if err != nil {
+		return err
+	}
+
+	return pubsub.Publish(ctx, TargetPingedTopic, string(msg))
+}
+
+func DecodePing(msg string) (Ping, error) {
+	var ping Ping
+	err := json.Unmarshal([]byte(msg), &ping)
+
+	return ping, err
+}
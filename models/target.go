@@ -5,10 +5,13 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"slices"
+	"strings"
 	"time"
 
 	"github.com/tehlordvortex/updawg/config"
 	"github.com/tehlordvortex/updawg/pubsub"
+	"github.com/tehlordvortex/updawg/schedule"
 )
 
 const (
@@ -16,30 +19,384 @@ const (
 	TargetCreatedTopic   = "target.created"
 	TargetDeletedTopic   = "target.deleted"
 	TargetUpdatedTopic   = "target.updated"
+	// TargetStatusChangedTopic is published with the target's id whenever
+	// its status changes.
+	TargetStatusChangedTopic = "target.status_changed"
+	TargetPausedTopic        = "target.paused"
+	TargetResumedTopic       = "target.resumed"
 )
 
+const (
+	TargetKindHttp        = "http"
+	TargetKindHeartbeat   = "heartbeat"
+	TargetKindExec        = "exec"
+	TargetKindSql         = "sql"
+	TargetKindSmtp        = "smtp"
+	TargetKindImap        = "imap"
+	TargetKindPop3        = "pop3"
+	TargetKindUdp         = "udp"
+	TargetKindIcmp        = "icmp"
+	TargetKindTransaction = "transaction"
+	TargetKindContent     = "content"
+	TargetKindDomain      = "domain"
+	TargetKindSsh         = "ssh"
+	TargetKindComposite   = "composite"
+	TargetKindTls         = "tls"
+)
+
+var TargetKinds = []string{
+	TargetKindHttp,
+	TargetKindHeartbeat,
+	TargetKindExec,
+	TargetKindSql,
+	TargetKindSmtp,
+	TargetKindImap,
+	TargetKindPop3,
+	TargetKindUdp,
+	TargetKindIcmp,
+	TargetKindTransaction,
+	TargetKindContent,
+	TargetKindDomain,
+	TargetKindSsh,
+	TargetKindComposite,
+	TargetKindTls,
+}
+
+// targetKindsWithoutUri are kinds which don't make requests to a uri of their
+// own, or like sql targets keep their address in their config as it may
+// contain credentials.
+var targetKindsWithoutUri = []string{TargetKindHeartbeat, TargetKindTransaction, TargetKindComposite, TargetKindSql}
+
 type Target struct {
-	pk        int64
-	id        string
-	Name      string
-	Uri       string
-	Method    string
-	Period    int64
-	config    map[string]interface{}
-	createdAt time.Time
-	updatedAt time.Time
+	pk     int64
+	id     string
+	Name   string
+	Uri    string
+	Method string
+	Kind   string
+	Period int64
+	// ParentIds are the targets this target depends on. While a parent is
+	// down, failures of this target are recorded as unreachable.
+	ParentIds []string
+	// Schedule is an optional cron expression used instead of Period.
+	Schedule string
+	// ActiveHours optionally restricts checks to windows such as
+	// "mon-fri 09:00-17:00". Both it and Schedule are in Timezone, or the
+	// local time zone if empty.
+	ActiveHours string
+	Timezone    string
+	config      map[string]interface{}
+	createdAt   time.Time
+	updatedAt   time.Time
+
+	status          Status
+	statusChangedAt time.Time
+
+	enabled     bool
+	pausedUntil time.Time
 }
 
 func (t *Target) Pk() int64            { return t.pk }
 func (t *Target) Id() string           { return t.id }
 func (t *Target) CreatedAt() time.Time { return t.createdAt }
 func (t *Target) UpdatedAt() time.Time { return t.updatedAt }
+func (t *Target) Status() Status {
+	if t.status == "" {
+		return StatusUnknown
+	}
+
+	return t.status
+}
+func (t *Target) StatusChangedAt() time.Time { return t.statusChangedAt }
+
+// Enabled is false while the target is paused.
+func (t *Target) Enabled() bool { return t.enabled }
+
+// PausedUntil is when a paused target is automatically resumed, or the zero
+// time if it is paused until resumed by hand.
+func (t *Target) PausedUntil() time.Time { return t.pausedUntil }
 func (t *Target) DisplayName() string {
-	if t.Name == "" {
+	if t.Name != "" {
+		return t.Name
+	} else if t.Uri != "" {
 		return t.Uri
 	} else {
-		return t.Name
+		return t.id
+	}
+}
+
+// Config returns the kind specific configuration of the target.
+func (t *Target) Config() map[string]interface{} {
+	if t.config == nil {
+		t.config = make(map[string]interface{})
+	}
+
+	return t.config
+}
+
+// SetConfig merges the given keys into the target's configuration. A nil
+// value removes the key.
+func (t *Target) SetConfig(config map[string]interface{}) {
+	for key, value := range config {
+		if value == nil {
+			delete(t.Config(), key)
+		} else {
+			t.Config()[key] = value
+		}
+	}
+}
+
+// DecodeConfig unmarshals the target's configuration into v, which should be
+// a pointer to a struct with json tags.
+func (t *Target) DecodeConfig(v interface{}) error {
+	configJson, err := json.Marshal(t.Config())
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(configJson, v)
+}
+
+// targetJson is how targets are sent to agents, which check them without
+// storing them.
+type targetJson struct {
+	Id          string                 `json:"id"`
+	Name        string                 `json:"name,omitempty"`
+	Uri         string                 `json:"uri"`
+	Method      string                 `json:"method,omitempty"`
+	Kind        string                 `json:"kind"`
+	Period      int64                  `json:"period"`
+	Schedule    string                 `json:"schedule,omitempty"`
+	ActiveHours string                 `json:"active_hours,omitempty"`
+	Timezone    string                 `json:"timezone,omitempty"`
+	Config      map[string]interface{} `json:"config"`
+	UpdatedAt   int64                  `json:"updated_at"`
+}
+
+func (t Target) MarshalJSON() ([]byte, error) {
+	return json.Marshal(targetJson{
+		Id:          t.id,
+		Name:        t.Name,
+		Uri:         t.Uri,
+		Method:      t.Method,
+		Kind:        t.Kind,
+		Period:      t.Period,
+		Schedule:    t.Schedule,
+		ActiveHours: t.ActiveHours,
+		Timezone:    t.Timezone,
+		Config:      t.Config(),
+		UpdatedAt:   t.updatedAt.Unix(),
+	})
+}
+
+// UnmarshalJSON decodes a target sent to an agent. The target isn't
+// persisted, so can be checked but not saved.
+func (t *Target) UnmarshalJSON(data []byte) error {
+	var v targetJson
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
 	}
+
+	*t = Target{
+		id:          v.Id,
+		Name:        v.Name,
+		Uri:         v.Uri,
+		Method:      v.Method,
+		Kind:        v.Kind,
+		Period:      v.Period,
+		Schedule:    v.Schedule,
+		ActiveHours: v.ActiveHours,
+		Timezone:    v.Timezone,
+		config:      v.Config,
+		updatedAt:   time.Unix(v.UpdatedAt, 0),
+		enabled:     true,
+	}
+
+	return nil
+}
+
+func (t *Target) String() string {
+	s := fmt.Sprintf("id=%s kind=%s name=%q uri=%s period=%d status=%s", t.id, t.Kind, t.Name, t.Uri, t.Period, t.Status())
+	if len(t.ParentIds) > 0 {
+		s += " depends_on=" + strings.Join(t.ParentIds, ",")
+	}
+
+	if t.Schedule != "" {
+		s += fmt.Sprintf(" schedule=%q", t.Schedule)
+	}
+
+	if t.ActiveHours != "" {
+		s += fmt.Sprintf(" active_hours=%q", t.ActiveHours)
+	}
+
+	if t.Timezone != "" {
+		s += " timezone=" + t.Timezone
+	}
+
+	if !t.Enabled() && !t.pausedUntil.IsZero() {
+		s += " paused_until=" + t.pausedUntil.Format(time.RFC3339)
+	} else if !t.Enabled() {
+		s += " paused"
+	}
+
+	return s
+}
+
+// TargetSchedule is a target's parsed Schedule, ActiveHours and Timezone.
+type TargetSchedule struct {
+	// Cron is nil if the target is checked every Period.
+	Cron        *schedule.Cron
+	ActiveHours schedule.ActiveHours
+	Location    *time.Location
+}
+
+// IsActive reports whether the target should be checked at t.
+func (s TargetSchedule) IsActive(t time.Time) bool {
+	return s.ActiveHours.Contains(t.In(s.Location))
+}
+
+// ParseSchedule parses the target's schedule settings. Save rejects targets
+// for which it fails.
+func (t *Target) ParseSchedule() (TargetSchedule, error) {
+	var s TargetSchedule
+	var err error
+
+	if s.Location, err = schedule.LoadLocation(t.Timezone); err != nil {
+		return s, fmt.Errorf("invalid timezone: %v", err)
+	}
+
+	if t.Schedule != "" {
+		if s.Cron, err = schedule.ParseCron(t.Schedule); err != nil {
+			return s, fmt.Errorf("invalid schedule: %v", err)
+		}
+	}
+
+	if s.ActiveHours, err = schedule.ParseActiveHours(t.ActiveHours); err != nil {
+		return s, fmt.Errorf("invalid active hours: %v", err)
+	}
+
+	return s, nil
+}
+
+func (t *Target) requiresUri() bool {
+	return !slices.Contains(targetKindsWithoutUri, t.Kind)
+}
+
+// UpdateStatus stores the target's latest status, publishing
+// TargetStatusChangedTopic if it differs from the previous one. Unlike Save
+// it doesn't publish TargetUpdatedTopic.
+func (t *Target) UpdateStatus(ctx context.Context, qe QueryExecutor, status Status) (bool, error) {
+	if t.pk == -1 {
+		return false, ErrRecordDeleted
+	} else if t.pk == 0 && t.id == "" {
+		return false, ErrRecordNotPersisted
+	}
+
+	if t.status == status {
+		return false, nil
+	}
+
+	unix := time.Now().UTC().Unix()
+
+	_, err := qe.ExecContext(ctx, "UPDATE targets SET (status, status_changed_at) = (?, ?) WHERE pk = ?", status, unix, t.pk)
+	if err != nil {
+		return false, fmt.Errorf("target.UpdateStatus(%s): %v", t.id, err)
+	}
+
+	t.status = status
+	t.statusChangedAt = time.Unix(unix, 0)
+
+	_ = pubsub.Publish(ctx, TargetStatusChangedTopic, t.id)
+	return true, nil
+}
+
+// Pause stops the target being monitored until it is resumed, or until the
+// given time unless it is zero. Like UpdateStatus, it doesn't publish
+// TargetUpdatedTopic.
+func (t *Target) Pause(ctx context.Context, qe QueryExecutor, until time.Time) error {
+	if t.pk == -1 {
+		return ErrRecordDeleted
+	} else if t.pk == 0 && t.id == "" {
+		return ErrRecordNotPersisted
+	}
+
+	var untilUnix sql.NullInt64
+	if !until.IsZero() {
+		untilUnix = sql.NullInt64{Int64: until.UTC().Unix(), Valid: true}
+	}
+
+	_, err := qe.ExecContext(ctx, "UPDATE targets SET (enabled, paused_until) = (0, ?) WHERE pk = ?", untilUnix, t.pk)
+	if err != nil {
+		return fmt.Errorf("target.Pause(%s): %v", t.id, err)
+	}
+
+	t.enabled = false
+	t.pausedUntil = time.Time{}
+	if untilUnix.Valid {
+		t.pausedUntil = time.Unix(untilUnix.Int64, 0)
+	}
+
+	_ = pubsub.Publish(ctx, TargetPausedTopic, t.id)
+	return nil
+}
+
+// Resume restarts monitoring of a paused target.
+func (t *Target) Resume(ctx context.Context, qe QueryExecutor) error {
+	if t.pk == -1 {
+		return ErrRecordDeleted
+	} else if t.pk == 0 && t.id == "" {
+		return ErrRecordNotPersisted
+	}
+
+	_, err := qe.ExecContext(ctx, "UPDATE targets SET (enabled, paused_until) = (1, NULL) WHERE pk = ?", t.pk)
+	if err != nil {
+		return fmt.Errorf("target.Resume(%s): %v", t.id, err)
+	}
+
+	t.enabled = true
+	t.pausedUntil = time.Time{}
+
+	_ = pubsub.Publish(ctx, TargetResumedTopic, t.id)
+	return nil
+}
+
+// checkDependencyCycle walks the target's parents, their parents and so on,
+// returning an error if the target is among them. Only saved targets can be
+// depended on, so new targets can't be part of a cycle.
+func (t *Target) checkDependencyCycle(ctx context.Context, qe QueryExecutor) error {
+	if t.id == "" {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	next := t.ParentIds
+
+	for len(next) > 0 {
+		parents, err := FindTargetsByIds(ctx, qe, next)
+		if err != nil {
+			return fmt.Errorf("target.Save(%s): %v", t.id, err)
+		}
+
+		next = nil
+		for _, parent := range parents {
+			if seen[parent.id] {
+				continue
+			}
+			seen[parent.id] = true
+
+			for _, id := range parent.ParentIds {
+				if id == t.id {
+					return fmt.Errorf("dependency cycle: %s depends on %s", parent.id, t.id)
+				}
+
+				if !seen[id] {
+					next = append(next, id)
+				}
+			}
+		}
+	}
+
+	return nil
 }
 
 // Target impl PassiveRecord
@@ -65,10 +422,21 @@ func (t *Target) Reload(ctx context.Context, qe QueryExecutor) error {
 func (t *Target) Save(ctx context.Context, qe QueryExecutor) error {
 	unix := time.Now().UTC().Unix()
 
-	if t.Uri == "" {
+	if t.Kind == "" {
+		t.Kind = TargetKindHttp
+	} else if !slices.Contains(TargetKinds, t.Kind) {
+		return fmt.Errorf("unknown target kind: %s", t.Kind)
+	}
+
+	if t.Uri == "" && t.requiresUri() {
 		return fmt.Errorf("target must have a uri")
 	}
 
+	// The uri is shown in logs and listings
+	if t.Uri != "" && t.Kind == TargetKindSql {
+		return fmt.Errorf("sql targets take their DSN from the dsn config key rather than the uri")
+	}
+
 	if t.Period == 0 {
 		t.Period = config.DefaultPeriod
 	} else if t.Period < 0 {
@@ -79,10 +447,42 @@ func (t *Target) Save(ctx context.Context, qe QueryExecutor) error {
 		t.Method = config.DefaultMethod
 	}
 
+	if t.Schedule != "" && t.Kind == TargetKindHeartbeat {
+		return fmt.Errorf("heartbeat targets cannot have a schedule")
+	}
+
+	if s, err := t.ParseSchedule(); err != nil {
+		return err
+	} else if s.Cron != nil && schedule.Next(time.Now().In(s.Location), s.Cron.Next, s.ActiveHours).IsZero() {
+		return fmt.Errorf("schedule never falls within active hours")
+	}
+
+	if t.id != "" && slices.Contains(t.ParentIds, t.id) {
+		return fmt.Errorf("target cannot depend on itself")
+	}
+
+	if err := t.checkDependencyCycle(ctx, qe); err != nil {
+		return err
+	}
+
+	if t.ParentIds == nil {
+		t.ParentIds = []string{}
+	}
+
+	parentIdsJson, err := json.Marshal(t.ParentIds)
+	if err != nil {
+		return fmt.Errorf("target.Save: %v", err)
+	}
+
+	configJson, err := json.Marshal(t.Config())
+	if err != nil {
+		return fmt.Errorf("target.Save: %v", err)
+	}
+
 	if t.pk == 0 && t.id == "" {
 		id := GenUlid("target")
 
-		result, err := qe.ExecContext(ctx, "INSERT INTO targets (id, name, uri, period, created_at, updated_at, method) VALUES (?, ?, ?, ?, ?, ?, ?)", id, t.Name, t.Uri, t.Period, unix, unix, t.Method)
+		result, err := qe.ExecContext(ctx, "INSERT INTO targets (id, name, uri, period, config, created_at, updated_at, method, kind, parent_ids, schedule, timezone, active_hours) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)", id, t.Name, t.Uri, t.Period, string(configJson), unix, unix, t.Method, t.Kind, string(parentIdsJson), t.Schedule, t.Timezone, t.ActiveHours)
 		if err != nil {
 			return fmt.Errorf("target.Save: %v", err)
 		}
@@ -94,6 +494,7 @@ func (t *Target) Save(ctx context.Context, qe QueryExecutor) error {
 
 		t.pk = pk
 		t.id = id
+		t.enabled = true
 		t.createdAt = time.Unix(unix, 0)
 		t.updatedAt = time.Unix(unix, 0)
 
@@ -101,7 +502,7 @@ func (t *Target) Save(ctx context.Context, qe QueryExecutor) error {
 		return nil
 	}
 
-	_, err := qe.ExecContext(ctx, "UPDATE targets SET (name, uri, period, updated_at, method) = (?, ?, ?, ?, ?) WHERE pk = ?", t.Name, t.Uri, t.Period, unix, t.Method, t.pk)
+	_, err = qe.ExecContext(ctx, "UPDATE targets SET (name, uri, period, config, updated_at, method, kind, parent_ids, schedule, timezone, active_hours) = (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?) WHERE pk = ?", t.Name, t.Uri, t.Period, string(configJson), unix, t.Method, t.Kind, string(parentIdsJson), t.Schedule, t.Timezone, t.ActiveHours, t.pk)
 	if err != nil {
 		return fmt.Errorf("target.Save(%s): %v", t.id, err)
 	}
@@ -138,15 +539,62 @@ func FindAllTargets(ctx context.Context, qe QueryExecutor) ([]Target, error) {
 	return LoadTargets(rows)
 }
 
+// FindAllTargetIds returns the id of every target.
+func FindAllTargetIds(ctx context.Context, qe QueryExecutor) ([]string, error) {
+	rows, err := qe.QueryContext(ctx, "SELECT id FROM targets")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return loadIds(rows)
+}
+
+// FindAllActiveTargets returns the targets which aren't paused.
 func FindAllActiveTargets(ctx context.Context, qe QueryExecutor) ([]Target, error) {
-	// TODO: Disable/enable targets
-	return FindAllTargets(ctx, qe)
+	rows, err := qe.QueryContext(ctx, "SELECT * FROM targets WHERE enabled = 1")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return LoadTargets(rows)
+}
+
+func FindAllPausedTargets(ctx context.Context, qe QueryExecutor) ([]Target, error) {
+	rows, err := qe.QueryContext(ctx, "SELECT * FROM targets WHERE enabled = 0")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return LoadTargets(rows)
 }
 
 func FindTargetById(ctx context.Context, qe QueryExecutor, id string) (Target, error) {
 	return LoadTarget(qe.QueryRowContext(ctx, "SELECT * FROM targets WHERE id = ?", id))
 }
 
+func FindTargetsByIds(ctx context.Context, qe QueryExecutor, ids []string) ([]Target, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(ids)), ", ")
+	rows, err := qe.QueryContext(ctx, "SELECT * FROM targets WHERE id IN ("+placeholders+")", args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return LoadTargets(rows)
+}
+
 func FindTargetsByIdPrefix(ctx context.Context, qe QueryExecutor, prefix string) ([]Target, error) {
 	rows, err := qe.QueryContext(ctx, "SELECT * FROM targets WHERE id LIKE ?", prefix+"%")
 	if err != nil {
@@ -190,10 +638,11 @@ func LoadTargets(rows *sql.Rows) ([]Target, error) {
 
 func loadTarget(t *Target, Scan PassiveRecordScanFunc) error {
 	var nameNullable, methodNullable sql.NullString
-	var configJson string
+	var configJson, parentIdsJson string
 	var createdAtUnix, updatedAtUnix int64
+	var statusChangedAtNullable, pausedUntilNullable sql.NullInt64
 
-	cols := []interface{}{&t.pk, &t.id, &nameNullable, &t.Uri, &t.Period, &configJson, &createdAtUnix, &updatedAtUnix, &methodNullable}
+	cols := []interface{}{&t.pk, &t.id, &nameNullable, &t.Uri, &t.Period, &configJson, &createdAtUnix, &updatedAtUnix, &methodNullable, &t.Kind, &t.status, &statusChangedAtNullable, &parentIdsJson, &t.Schedule, &t.Timezone, &t.ActiveHours, &t.enabled, &pausedUntilNullable}
 	err := Scan(cols)
 	if err != nil {
 		return err
@@ -209,13 +658,32 @@ func loadTarget(t *Target, Scan PassiveRecordScanFunc) error {
 		t.Method = config.DefaultMethod
 	}
 
+	t.config = nil
 	err = json.Unmarshal([]byte(configJson), &t.config)
 	if err != nil {
 		return err
 	}
 
+	t.ParentIds = nil
+	err = json.Unmarshal([]byte(parentIdsJson), &t.ParentIds)
+	if err != nil {
+		return err
+	}
+
 	t.createdAt = time.Unix(createdAtUnix, 0)
 	t.updatedAt = time.Unix(updatedAtUnix, 0)
 
+	if statusChangedAtNullable.Valid {
+		t.statusChangedAt = time.Unix(statusChangedAtNullable.Int64, 0)
+	} else {
+		t.statusChangedAt = time.Time{}
+	}
+
+	if pausedUntilNullable.Valid {
+		t.pausedUntil = time.Unix(pausedUntilNullable.Int64, 0)
+	} else {
+		t.pausedUntil = time.Time{}
+	}
+
 	return nil
 }
@@ -0,0 +1,93 @@
+package models
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+)
+
+const TargetLeaseModelTableName = "target_leases"
+
+// Target leases make sure only one updawg serve instance monitors each
+// target. A lease is held until it expires unless renewed by its instance.
+
+// ClaimTargetLease takes the lease on a target for an instance until
+// expiresAt, unless another instance holds a lease which hasn't expired. It
+// reports whether the instance now holds the lease.
+func ClaimTargetLease(ctx context.Context, qe QueryExecutor, targetId, instanceId string, expiresAt time.Time) (bool, error) {
+	now := time.Now().UTC().Unix()
+	if expiresAt.UTC().Unix() <= now {
+		return false, fmt.Errorf("ClaimTargetLease(%s): lease would already have expired at %s", targetId, expiresAt.UTC().Format(time.RFC3339))
+	}
+
+	result, err := qe.ExecContext(ctx, `
+INSERT INTO target_leases (target_id, instance_id, expires_at) VALUES (?, ?, ?)
+ON CONFLICT (target_id) DO UPDATE SET (instance_id, expires_at) = (excluded.instance_id, excluded.expires_at)
+WHERE target_leases.instance_id = excluded.instance_id OR target_leases.expires_at <= ?`, targetId, instanceId, expiresAt.UTC().Unix(), now)
+	if err != nil {
+		return false, fmt.Errorf("ClaimTargetLease(%s): %v", targetId, err)
+	}
+
+	claimed, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("ClaimTargetLease(%s): %v", targetId, err)
+	}
+
+	return claimed > 0, nil
+}
+
+// RenewTargetLeases extends every lease held by an instance until expiresAt.
+func RenewTargetLeases(ctx context.Context, qe QueryExecutor, instanceId string, expiresAt time.Time) error {
+	_, err := qe.ExecContext(ctx, "UPDATE target_leases SET expires_at = ? WHERE instance_id = ?", expiresAt.UTC().Unix(), instanceId)
+	if err != nil {
+		return fmt.Errorf("RenewTargetLeases(%s): %v", instanceId, err)
+	}
+
+	return nil
+}
+
+func ReleaseTargetLease(ctx context.Context, qe QueryExecutor, targetId, instanceId string) error {
+	_, err := qe.ExecContext(ctx, "DELETE FROM target_leases WHERE target_id = ? AND instance_id = ?", targetId, instanceId)
+	if err != nil {
+		return fmt.Errorf("ReleaseTargetLease(%s): %v", targetId, err)
+	}
+
+	return nil
+}
+
+func ReleaseTargetLeases(ctx context.Context, qe QueryExecutor, instanceId string) error {
+	_, err := qe.ExecContext(ctx, "DELETE FROM target_leases WHERE instance_id = ?", instanceId)
+	if err != nil {
+		return fmt.Errorf("ReleaseTargetLeases(%s): %v", instanceId, err)
+	}
+
+	return nil
+}
+
+// FindTargetLeaseHolder returns the instance holding the lease on a target,
+// even if it has expired, or an empty string if nobody does.
+func FindTargetLeaseHolder(ctx context.Context, qe QueryExecutor, targetId string) (string, error) {
+	var instanceId string
+	err := qe.QueryRowContext(ctx, "SELECT instance_id FROM target_leases WHERE target_id = ?", targetId).Scan(&instanceId)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	} else if err != nil {
+		return "", fmt.Errorf("FindTargetLeaseHolder(%s): %v", targetId, err)
+	}
+
+	return instanceId, nil
+}
+
+// FindTargetIdsLeasedBy returns the ids of the targets an instance holds a
+// lease on which hasn't expired.
+func FindTargetIdsLeasedBy(ctx context.Context, qe QueryExecutor, instanceId string) ([]string, error) {
+	rows, err := qe.QueryContext(ctx, "SELECT target_id FROM target_leases WHERE instance_id = ? AND expires_at > ?", instanceId, time.Now().UTC().Unix())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	return loadIds(rows)
+}
@@ -36,6 +36,7 @@ type Message struct {
 
 var (
 	db        *sql.DB
+	stopped   chan struct{}
 	mut       = &sync.Mutex{}
 	topicSubs = make(map[string][]chan Message)
 	logger    = log.New(config.GetLogFile(), "", log.Default().Flags()|log.Lmsgprefix|log.Lshortfile)
@@ -63,9 +64,12 @@ func Run(ctx context.Context) {
 	}
 
 	db = _db
+	stopped = make(chan struct{})
 	logger.Printf("pubsub streaming messages after ts=%d", lastTsUnix)
 
 	go func() {
+		defer close(stopped)
+
 		getTopics := func() []string {
 			mut.Lock()
 			defer mut.Unlock()
@@ -95,7 +99,9 @@ func Run(ctx context.Context) {
 			default:
 				messages, lastTs, err := loadMessagesForTopics(ctx, getTopics(), lastTsUnix)
 				if err != nil {
-					logger.Println(err)
+					if ctx.Err() == nil {
+						logger.Println(err)
+					}
 					return
 				}
 
@@ -113,6 +119,20 @@ func Run(ctx context.Context) {
 	}()
 }
 
+// Close waits for messages to stop being delivered, once the context given
+// to Run is done, and closes the database.
+func Close() {
+	if db == nil {
+		return
+	}
+
+	<-stopped
+
+	if err := db.Close(); err != nil {
+		logger.Println("failed to close pubsub:", err)
+	}
+}
+
 func Publish(ctx context.Context, topic string, message string) error {
 	if db == nil {
 		logger.Printf("pubsub.Publish(%s): failed: %v", topic, ErrNotRunning)
@@ -0,0 +1,143 @@
+package schedule
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// window is a daily time range on a set of weekdays. Ranges ending before
+// they start run past midnight into the next day.
+type window struct {
+	days       [7]bool
+	start, end int // minutes since midnight
+}
+
+// ActiveHours restricts checks to a set of weekly windows. The zero value is
+// always active.
+type ActiveHours []window
+
+// ParseActiveHours parses a comma separated list of windows, each an
+// optional weekday or weekday range followed by a time range, such as
+// "mon-fri 09:00-17:00, sat 10:00-14:00" or "22:00-06:00".
+func ParseActiveHours(s string) (ActiveHours, error) {
+	var hours ActiveHours
+
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		fields := strings.Fields(part)
+		if len(fields) > 2 {
+			return nil, fmt.Errorf("invalid active hours %q", part)
+		}
+
+		var w window
+		if len(fields) == 2 {
+			days, err := parseCronField(fields[0], 0, 7, weekdayNames)
+			if err != nil {
+				return nil, fmt.Errorf("invalid days %q: %v", fields[0], err)
+			}
+
+			for day := range w.days {
+				w.days[day] = days.has(day) || (day == 0 && days.has(7))
+			}
+		} else {
+			w.days = [7]bool{true, true, true, true, true, true, true}
+		}
+
+		startPart, endPart, ok := strings.Cut(fields[len(fields)-1], "-")
+		if !ok {
+			return nil, fmt.Errorf("invalid time range %q", fields[len(fields)-1])
+		}
+
+		var err error
+		if w.start, err = parseClock(startPart); err != nil {
+			return nil, err
+		}
+
+		if w.end, err = parseClock(endPart); err != nil {
+			return nil, err
+		}
+
+		if w.start == w.end {
+			return nil, fmt.Errorf("empty time range %q", fields[len(fields)-1])
+		}
+
+		hours = append(hours, w)
+	}
+
+	return hours, nil
+}
+
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		// 24:00 is allowed as the end of the day
+		if s == "24:00" {
+			return 24 * 60, nil
+		}
+
+		return 0, fmt.Errorf("invalid time %q", s)
+	}
+
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// Contains reports whether t, in its own location, is within any window.
+func (a ActiveHours) Contains(t time.Time) bool {
+	if len(a) == 0 {
+		return true
+	}
+
+	minute := t.Hour()*60 + t.Minute()
+	today := int(t.Weekday())
+	yesterday := (today + 6) % 7
+
+	for _, w := range a {
+		if w.start < w.end {
+			if w.days[today] && minute >= w.start && minute < w.end {
+				return true
+			}
+		} else if (w.days[today] && minute >= w.start) || (w.days[yesterday] && minute < w.end) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Next returns t if it is within a window, or otherwise the start of the
+// next window after it.
+func (a ActiveHours) Next(t time.Time) time.Time {
+	if a.Contains(t) {
+		return t
+	}
+
+	loc := t.Location()
+	year, month, day := t.Date()
+
+	var next time.Time
+	for offset := 0; offset <= 7; offset++ {
+		date := time.Date(year, month, day+offset, 0, 0, 0, 0, loc)
+
+		for _, w := range a {
+			if !w.days[int(date.Weekday())] {
+				continue
+			}
+
+			start := time.Date(year, month, day+offset, 0, w.start, 0, 0, loc)
+			if start.After(t) && (next.IsZero() || start.Before(next)) {
+				next = start
+			}
+		}
+
+		if !next.IsZero() {
+			return next
+		}
+	}
+
+	return t
+}
@@ -0,0 +1,92 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+// 2024-01-01 is a Monday
+func at(day, hour, minute int) time.Time {
+	return time.Date(2024, 1, day, hour, minute, 0, 0, time.UTC)
+}
+
+func TestParseActiveHoursErrors(t *testing.T) {
+	tests := []string{
+		"09:00",
+		"mon 09:00",
+		"mon-fri 9-17",
+		"09:00-09:00",
+		"mon tue 09:00-10:00",
+		"xyz 09:00-10:00",
+		"25:00-26:00",
+		"mon-fri 09:00-17:00, sat",
+	}
+
+	for _, s := range tests {
+		if _, err := ParseActiveHours(s); err == nil {
+			t.Errorf("ParseActiveHours(%q) succeeded, want an error", s)
+		}
+	}
+}
+
+func TestActiveHoursContains(t *testing.T) {
+	tests := []struct {
+		hours string
+		at    time.Time
+		want  bool
+	}{
+		{"", at(6, 3, 0), true},
+		{"mon-fri 09:00-17:00", at(1, 10, 0), true},
+		{"mon-fri 09:00-17:00", at(1, 9, 0), true},
+		{"mon-fri 09:00-17:00", at(1, 17, 0), false},
+		{"mon-fri 09:00-17:00", at(1, 8, 59), false},
+		{"mon-fri 09:00-17:00", at(6, 10, 0), false},
+		{"mon-fri 09:00-17:00, sat 10:00-14:00", at(6, 10, 0), true},
+		{"22:00-06:00", at(3, 23, 0), true},
+		{"22:00-06:00", at(3, 5, 59), true},
+		{"22:00-06:00", at(3, 6, 0), false},
+		{"22:00-06:00", at(3, 12, 0), false},
+		// Overnight windows continue into the next day
+		{"fri 22:00-02:00", at(6, 1, 0), true},
+		{"fri 22:00-02:00", at(5, 1, 0), false},
+		{"7 10:00-12:00", at(7, 11, 0), true},
+		{"sun 10:00-12:00", at(7, 11, 0), true},
+		{"09:00-24:00", at(1, 23, 59), true},
+	}
+
+	for _, tt := range tests {
+		hours, err := ParseActiveHours(tt.hours)
+		if err != nil {
+			t.Fatalf("ParseActiveHours(%q): %v", tt.hours, err)
+		}
+
+		if got := hours.Contains(tt.at); got != tt.want {
+			t.Errorf("ParseActiveHours(%q).Contains(%s) = %v, want %v", tt.hours, tt.at.Format(time.RFC1123), got, tt.want)
+		}
+	}
+}
+
+func TestActiveHoursNext(t *testing.T) {
+	tests := []struct {
+		hours string
+		from  time.Time
+		want  time.Time
+	}{
+		{"mon-fri 09:00-17:00", at(1, 10, 0), at(1, 10, 0)},
+		{"mon-fri 09:00-17:00", at(1, 8, 0), at(1, 9, 0)},
+		{"mon-fri 09:00-17:00", at(5, 18, 0), at(8, 9, 0)},
+		{"mon 09:00-10:00, wed 09:00-10:00", at(1, 11, 0), at(3, 9, 0)},
+		{"22:00-06:00", at(1, 12, 0), at(1, 22, 0)},
+	}
+
+	for _, tt := range tests {
+		hours, err := ParseActiveHours(tt.hours)
+		if err != nil {
+			t.Fatalf("ParseActiveHours(%q): %v", tt.hours, err)
+		}
+
+		if got := hours.Next(tt.from); !got.Equal(tt.want) {
+			t.Errorf("ParseActiveHours(%q).Next(%s) = %s, want %s", tt.hours, tt.from, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package schedule
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxCronSearch bounds how far ahead Next looks for a matching time, so
+// expressions which never match (such as February 30th) terminate.
+const maxCronSearch = 5 * 366 * 24 * time.Hour
+
+var cronDescriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+var (
+	monthNames   = []string{"jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"}
+	weekdayNames = []string{"sun", "mon", "tue", "wed", "thu", "fri", "sat"}
+)
+
+// bits is a set of the values of a cron field.
+type bits uint64
+
+func (b bits) has(v int) bool { return b&(1<<uint(v)) != 0 }
+
+// Cron is a parsed five field cron expression: minute, hour, day of month,
+// month and day of week.
+type Cron struct {
+	minute, hour, dom, month, dow bits
+	// Day of month and day of week match if either does, unless one of them
+	// is *, as in standard cron.
+	domStar, dowStar bool
+}
+
+// ParseCron parses a standard five field cron expression, or one of the
+// @hourly, @daily, @weekly, @monthly and @yearly descriptors. Fields accept
+// *, values, ranges, steps and lists, with names for months and weekdays.
+func ParseCron(expr string) (*Cron, error) {
+	expr = strings.TrimSpace(expr)
+	if descriptor, ok := cronDescriptors[strings.ToLower(expr)]; ok {
+		expr = descriptor
+	}
+
+	fields := strings.Fields(expr)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("invalid cron expression %q: expected 5 fields", expr)
+	}
+
+	var c Cron
+	var err error
+
+	if c.minute, err = parseCronField(fields[0], 0, 59, nil); err != nil {
+		return nil, fmt.Errorf("invalid minute: %v", err)
+	}
+
+	if c.hour, err = parseCronField(fields[1], 0, 23, nil); err != nil {
+		return nil, fmt.Errorf("invalid hour: %v", err)
+	}
+
+	if c.dom, err = parseCronField(fields[2], 1, 31, nil); err != nil {
+		return nil, fmt.Errorf("invalid day of month: %v", err)
+	}
+
+	if c.month, err = parseCronField(fields[3], 1, 12, monthNames); err != nil {
+		return nil, fmt.Errorf("invalid month: %v", err)
+	}
+
+	// Sunday is either 0 or 7
+	if c.dow, err = parseCronField(fields[4], 0, 7, weekdayNames); err != nil {
+		return nil, fmt.Errorf("invalid day of week: %v", err)
+	}
+
+	if c.dow.has(7) {
+		c.dow |= 1
+	}
+
+	c.domStar = fields[2] == "*" || fields[2] == "?"
+	c.dowStar = fields[4] == "*" || fields[4] == "?"
+
+	return &c, nil
+}
+
+func parseCronField(field string, min, max int, names []string) (bits, error) {
+	var b bits
+
+	for _, part := range strings.Split(field, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+
+		step := 1
+		if hasStep {
+			var err error
+			if step, err = strconv.Atoi(stepPart); err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step %q", stepPart)
+			}
+		}
+
+		var start, end int
+		switch {
+		case rangePart == "*" || rangePart == "?":
+			start, end = min, max
+		case strings.Contains(rangePart, "-"):
+			startPart, endPart, _ := strings.Cut(rangePart, "-")
+
+			var err error
+			if start, err = parseCronValue(startPart, min, names); err != nil {
+				return 0, err
+			}
+
+			if end, err = parseCronValue(endPart, min, names); err != nil {
+				return 0, err
+			}
+		default:
+			var err error
+			if start, err = parseCronValue(rangePart, min, names); err != nil {
+				return 0, err
+			}
+
+			end = start
+			if hasStep {
+				end = max
+			}
+		}
+
+		if start < min || end > max || start > end {
+			return 0, fmt.Errorf("%q out of range %d-%d", part, min, max)
+		}
+
+		for v := start; v <= end; v += step {
+			b |= 1 << uint(v)
+		}
+	}
+
+	return b, nil
+}
+
+func parseCronValue(value string, min int, names []string) (int, error) {
+	for i, name := range names {
+		if strings.EqualFold(value, name) {
+			return i + min, nil
+		}
+	}
+
+	v, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", value)
+	}
+
+	return v, nil
+}
+
+// Next returns the first time after t matching the expression, in t's
+// location, or the zero time if nothing matches.
+func (c *Cron) Next(t time.Time) time.Time {
+	loc := t.Location()
+	limit := t.Add(maxCronSearch)
+
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	for t.Before(limit) {
+		year, month, day := t.Date()
+
+		if !c.month.has(int(month)) {
+			t = time.Date(year, month+1, 1, 0, 0, 0, 0, loc)
+			continue
+		}
+
+		if !c.dayMatches(t) {
+			t = time.Date(year, month, day+1, 0, 0, 0, 0, loc)
+			continue
+		}
+
+		if !c.hour.has(t.Hour()) {
+			t = time.Date(year, month, day, t.Hour()+1, 0, 0, 0, loc)
+			continue
+		}
+
+		if !c.minute.has(t.Minute()) {
+			t = t.Add(time.Minute)
+			continue
+		}
+
+		return t
+	}
+
+	return time.Time{}
+}
+
+func (c *Cron) dayMatches(t time.Time) bool {
+	domMatch := c.dom.has(t.Day())
+	dowMatch := c.dow.has(int(t.Weekday()))
+
+	if c.domStar || c.dowStar {
+		return domMatch && dowMatch
+	}
+
+	return domMatch || dowMatch
+}
@@ -0,0 +1,93 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseCronErrors(t *testing.T) {
+	tests := []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+		"* * * foo *",
+		"@every5m",
+	}
+
+	for _, expr := range tests {
+		if _, err := ParseCron(expr); err == nil {
+			t.Errorf("ParseCron(%q) succeeded, want an error", expr)
+		}
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	// 2024-01-01 is a Monday
+	at := func(month time.Month, day, hour, minute int) time.Time {
+		return time.Date(2024, month, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		expr string
+		from time.Time
+		want time.Time
+	}{
+		{"* * * * *", at(1, 1, 10, 7).Add(30 * time.Second), at(1, 1, 10, 8)},
+		{"*/15 * * * *", at(1, 1, 10, 7), at(1, 1, 10, 15)},
+		{"*/15 * * * *", at(1, 1, 10, 15), at(1, 1, 10, 30)},
+		{"5/20 * * * *", at(1, 1, 10, 30), at(1, 1, 10, 45)},
+		{"0,30 9-10 * * *", at(1, 1, 10, 30), at(1, 2, 9, 0)},
+		{"0 9 * * mon-fri", at(1, 5, 10, 0), at(1, 8, 9, 0)},
+		{"0 0 * * 7", at(1, 1, 0, 0), at(1, 7, 0, 0)},
+		{"0 0 * * SUN", at(1, 1, 0, 0), at(1, 7, 0, 0)},
+		{"@daily", at(1, 1, 0, 0), at(1, 2, 0, 0)},
+		{"@hourly", at(1, 1, 10, 59), at(1, 1, 11, 0)},
+		{"@monthly", at(1, 15, 0, 0), at(2, 1, 0, 0)},
+		// Day of month or day of week, as neither is *
+		{"0 0 15 * fri", at(1, 2, 0, 0), at(1, 5, 0, 0)},
+		{"0 0 3 * fri", at(1, 2, 0, 0), at(1, 3, 0, 0)},
+		{"0 12 * jan-mar/2 *", at(2, 1, 0, 0), at(3, 1, 12, 0)},
+		{"30 2 29 2 *", at(3, 1, 0, 0), time.Date(2028, 2, 29, 2, 30, 0, 0, time.UTC)},
+		{"0 0 30 feb *", at(1, 1, 0, 0), time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			c, err := ParseCron(tt.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if got := c.Next(tt.from); !got.Equal(tt.want) {
+				t.Errorf("Next(%s) = %s, want %s", tt.from, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCronNextKeepsLocation(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Paris")
+	if err != nil {
+		t.Skip(err)
+	}
+
+	c, err := ParseCron("0 9 * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	from := time.Date(2024, 1, 1, 10, 0, 0, 0, loc)
+	want := time.Date(2024, 1, 2, 9, 0, 0, 0, loc)
+
+	if got := c.Next(from); !got.Equal(want) || got.Location() != loc {
+		t.Errorf("Next(%s) = %s, want %s", from, got, want)
+	}
+}
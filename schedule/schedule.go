@@ -0,0 +1,38 @@
+// Package schedule works out when targets are due to be checked, from cron
+// expressions and active hours in a target's time zone.
+package schedule
+
+import (
+	"time"
+)
+
+// LoadLocation returns the named time zone, or the local time zone if name
+// is empty.
+func LoadLocation(name string) (*time.Location, error) {
+	if name == "" {
+		return time.Local, nil
+	}
+
+	return time.LoadLocation(name)
+}
+
+// maxWindowSkips bounds how many runs Next skips for falling outside active
+// hours before giving up.
+const maxWindowSkips = 1000
+
+// Next returns the first time after t produced by next which falls within
+// hours, or the zero time if there is none. next returns the first run after
+// the time it is given.
+func Next(t time.Time, next func(time.Time) time.Time, hours ActiveHours) time.Time {
+	t = next(t)
+	for i := 0; i < maxWindowSkips && !t.IsZero(); i++ {
+		if hours.Contains(t) {
+			return t
+		}
+
+		// Look for the first run at or after the next window starts
+		t = next(hours.Next(t).Add(-time.Nanosecond))
+	}
+
+	return time.Time{}
+}
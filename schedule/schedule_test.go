@@ -0,0 +1,44 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNext(t *testing.T) {
+	hourly, err := ParseCron("@hourly")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	midnight, err := ParseCron("@daily")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	workHours, err := ParseActiveHours("mon-fri 09:00-17:00")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		next  func(time.Time) time.Time
+		hours ActiveHours
+		from  time.Time
+		want  time.Time
+	}{
+		{"no active hours", hourly.Next, nil, at(6, 10, 30), at(6, 11, 0)},
+		{"within active hours", hourly.Next, workHours, at(1, 10, 30), at(1, 11, 0)},
+		{"skips to the next window", hourly.Next, workHours, at(5, 16, 30), at(8, 9, 0)},
+		{"never within active hours", midnight.Next, workHours, at(1, 10, 0), time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Next(tt.from, tt.next, tt.hours); !got.Equal(tt.want) {
+				t.Errorf("Next(%s) = %s, want %s", tt.from, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package server
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/tehlordvortex/updawg/agent"
+	"github.com/tehlordvortex/updawg/checks"
+	"github.com/tehlordvortex/updawg/models"
+)
+
+const maxReportsSize = 10 << 20
+
+// requireAgentToken only passes on requests bearing the agent token.
+func requireAgentToken(token string, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		bearer, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(bearer), []byte(token)) != 1 {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		h.ServeHTTP(w, r)
+	})
+}
+
+// registerAgentHandler registers an agent at POST /agents.
+func registerAgentHandler(db *sql.DB) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var registration agent.Registration
+		if err := json.NewDecoder(r.Body).Decode(&registration); err != nil {
+			http.Error(w, "invalid registration: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		a := models.Agent{Location: registration.Location, Hostname: registration.Hostname}
+		if err := a.Save(r.Context(), db); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		logger.Printf("agent registered id=%s location=%s hostname=%s\n", a.Id(), a.Location, a.Hostname)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(agent.Registered{Id: a.Id()})
+	})
+}
+
+// agentTargetsHandler lists the targets assigned to an agent's location at
+// GET /agents/{id}/targets.
+func agentTargetsHandler(db *sql.DB) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		a, ok := findAgent(w, r, db)
+		if !ok {
+			return
+		}
+
+		targets, err := models.FindAllActiveTargets(r.Context(), db)
+		if err != nil {
+			http.Error(w, "failed to load targets", http.StatusInternalServerError)
+			return
+		}
+
+		targets = slices.DeleteFunc(targets, func(target models.Target) bool {
+			return !slices.Contains(checks.Locations(&target), a.Location)
+		})
+		if targets == nil {
+			targets = []models.Target{}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(targets)
+	})
+}
+
+// agentResultsHandler receives an agent's check results at POST
+// /agents/{id}/results. A target is re-checked straight away when its
+// status at the agent's location changes.
+func agentResultsHandler(db *sql.DB) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		a, ok := findAgent(w, r, db)
+		if !ok {
+			return
+		}
+
+		var reports []agent.Report
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxReportsSize)).Decode(&reports); err != nil {
+			http.Error(w, "invalid results: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		for _, report := range reports {
+			target, err := models.FindTargetById(r.Context(), db, report.TargetId)
+			if err != nil || !slices.Contains(checks.Locations(&target), a.Location) {
+				logger.Printf("ignoring result for unassigned target id=%s agent_id=%s location=%s\n", report.TargetId, a.Id(), a.Location)
+				continue
+			}
+
+			previous, err := models.FindLocationResult(r.Context(), db, target.Id(), a.Location)
+			if err != nil && !errors.Is(err, sql.ErrNoRows) {
+				http.Error(w, "failed to load previous result", http.StatusInternalServerError)
+				return
+			}
+
+			result := models.LocationResult{
+				TargetId: target.Id(),
+				Location: a.Location,
+				AgentId:  a.Id(),
+				Status:   report.Status,
+				Message:  report.Message,
+				Duration: report.Duration(),
+				Data:     report.Data,
+			}
+
+			if err := result.Save(r.Context(), db); err != nil {
+				logger.Printf("failed to save location result id=%s location=%s error=%v\n", target.Id(), a.Location, err)
+				http.Error(w, "failed to save result", http.StatusInternalServerError)
+				return
+			}
+
+			if previous.Status != result.Status {
+				logger.Printf("location status changed id=%s location=%s from=%s to=%s\n", target.Id(), a.Location, previous.Status, result.Status)
+				if _, err := models.PublishCheckRequest(r.Context(), target.Id()); err != nil {
+					logger.Printf("failed to request check id=%s error=%v\n", target.Id(), err)
+				}
+			}
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// findAgent loads the agent a request is for, recording that it has been
+// seen, or responds with not found.
+func findAgent(w http.ResponseWriter, r *http.Request, db *sql.DB) (models.Agent, bool) {
+	a, err := models.FindAgentById(r.Context(), db, r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "unknown agent", http.StatusNotFound)
+		return models.Agent{}, false
+	}
+
+	if err := a.Touch(r.Context(), db); err != nil {
+		logger.Println(err)
+	}
+
+	return a, true
+}
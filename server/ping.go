@@ -0,0 +1,71 @@
+package server
+
+import (
+	"database/sql"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tehlordvortex/updawg/models"
+)
+
+const maxPingMessageLength = 1024
+
+// pingHandler receives heartbeats at /ping/{id} and /ping/{id}/{signal}. The
+// optional duration query parameter is either a Go duration string or a
+// number of seconds, and the request body is used as the result message.
+func pingHandler(db *sql.DB) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+		signal := r.PathValue("signal")
+		if signal == "" {
+			signal = models.PingSignalSuccess
+		}
+
+		if !models.IsPingSignal(signal) {
+			http.Error(w, "unknown signal: "+signal, http.StatusNotFound)
+			return
+		}
+
+		target, err := models.FindTargetById(r.Context(), db, id)
+		if err != nil || target.Kind != models.TargetKindHeartbeat {
+			http.Error(w, "not found", http.StatusNotFound)
+			return
+		}
+
+		ping := models.Ping{TargetId: target.Id(), Signal: signal}
+
+		if raw := r.URL.Query().Get("duration"); raw != "" {
+			duration, err := parseDuration(raw)
+			if err != nil {
+				http.Error(w, "invalid duration: "+raw, http.StatusBadRequest)
+				return
+			}
+
+			ping.Duration = duration
+		}
+
+		body, err := io.ReadAll(io.LimitReader(r.Body, maxPingMessageLength))
+		if err == nil {
+			ping.Message = strings.TrimSpace(string(body))
+		}
+
+		if err := models.PublishPing(r.Context(), ping); err != nil {
+			http.Error(w, "failed to record ping", http.StatusInternalServerError)
+			return
+		}
+
+		logger.Printf("ping received id=%s signal=%s\n", target.Id(), signal)
+		w.Write([]byte("OK\n"))
+	})
+}
+
+func parseDuration(raw string) (time.Duration, error) {
+	if seconds, err := strconv.ParseFloat(raw, 64); err == nil {
+		return time.Duration(seconds * float64(time.Second)), nil
+	}
+
+	return time.ParseDuration(raw)
+}
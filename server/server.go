@@ -0,0 +1,57 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/tehlordvortex/updawg/config"
+)
+
+var logger = log.New(config.GetLogFile(), "", log.Default().Flags()|log.Lmsgprefix|log.Lshortfile)
+
+// Run serves the HTTP endpoints used by updawg serve until ctx is cancelled
+// and requests in progress have finished.
+func Run(ctx context.Context, db *sql.DB, addr string) {
+	mux := http.NewServeMux()
+	mux.Handle("/ping/{id}", pingHandler(db))
+	mux.Handle("/ping/{id}/{signal}", pingHandler(db))
+
+	// Agents can only report results once they share a token with the server
+	if token := config.GetAgentToken(); token != "" {
+		mux.Handle("POST /agents", requireAgentToken(token, registerAgentHandler(db)))
+		mux.Handle("GET /agents/{id}/targets", requireAgentToken(token, agentTargetsHandler(db)))
+		mux.Handle("POST /agents/{id}/results", requireAgentToken(token, agentResultsHandler(db)))
+	}
+
+	srv := &http.Server{
+		Addr:              addr,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	// ListenAndServe returns as soon as shutdown starts, so wait for requests
+	// in progress to finish before returning
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		<-ctx.Done()
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		if err := srv.Shutdown(shutdownCtx); err != nil {
+			logger.Println("server shutdown failed:", err)
+		}
+	}()
+
+	logger.Printf("listening addr=%s\n", addr)
+	if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		logger.Fatalln("server failed:", err)
+	}
+
+	<-stopped
+}
@@ -0,0 +1,201 @@
+package workers
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+
+	"github.com/tehlordvortex/updawg/agent"
+	"github.com/tehlordvortex/updawg/checks"
+	"github.com/tehlordvortex/updawg/models"
+)
+
+const (
+	// reportInterval is how often agents send their results to the server.
+	reportInterval = time.Second
+	// maxPendingReports bounds the results an agent keeps while the server
+	// can't be reached, dropping the oldest.
+	maxPendingReports = 1000
+	// finalReportTimeout bounds sending the last results when stopping.
+	finalReportTimeout = 5 * time.Second
+)
+
+// AgentOptions configure updawg agent.
+type AgentOptions struct {
+	// PoolSize is the number of checks which may run at once.
+	PoolSize int
+	// Refresh is how often the targets assigned to the agent are fetched.
+	Refresh time.Duration
+}
+
+// RunAgent checks the targets assigned to the client's location and reports
+// their results to the server until ctx is done.
+func RunAgent(ctx context.Context, db *sql.DB, client *agent.Client, options AgentOptions) {
+	options.PoolSize = max(options.PoolSize, 1)
+
+	reports := make(chan agent.Report, options.PoolSize)
+	slots := make(chan struct{}, options.PoolSize)
+
+	reported := make(chan struct{})
+	go func() {
+		defer close(reported)
+		reportResults(ctx, client, reports)
+	}()
+
+	type agentTarget struct {
+		updatedAt time.Time
+		cancel    context.CancelFunc
+	}
+
+	var wg sync.WaitGroup
+	running := make(map[string]agentTarget)
+
+	refresh := func() {
+		targets, err := client.Targets(ctx)
+		if err != nil {
+			logger.Printf("failed to fetch targets error=%v\n", err)
+			return
+		}
+
+		assigned := make(map[string]bool)
+		for _, target := range targets {
+			assigned[target.Id()] = true
+
+			// Restart checking targets which have been updated
+			if r, ok := running[target.Id()]; ok && r.updatedAt.Equal(target.UpdatedAt()) {
+				continue
+			} else if ok {
+				r.cancel()
+			} else {
+				logger.Printf("starting monitoring for target id=%s name=%s\n", target.Id(), target.DisplayName())
+			}
+
+			targetCtx, cancel := context.WithCancel(ctx)
+			running[target.Id()] = agentTarget{updatedAt: target.UpdatedAt(), cancel: cancel}
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				runAgentTarget(targetCtx, db, target, slots, reports)
+			}()
+		}
+
+		for id, r := range running {
+			if !assigned[id] {
+				logger.Printf("stopping monitoring for unassigned target id=%s\n", id)
+				r.cancel()
+				delete(running, id)
+			}
+		}
+	}
+
+	refresh()
+
+	ticker := time.NewTicker(options.Refresh)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			wg.Wait()
+			<-reported
+			return
+		case <-ticker.C:
+			refresh()
+		}
+	}
+}
+
+// runAgentTarget checks the target whenever it is due, re-checking it sooner
+// while it fails as updawg serve does.
+func runAgentTarget(ctx context.Context, db *sql.DB, target models.Target, slots chan struct{}, reports chan<- agent.Report) {
+	var failures int
+
+	for {
+		next, _ := nextDue(&target, failures, time.Now())
+		if next.IsZero() {
+			logger.Printf("target is never due id=%s\n", target.Id())
+			return
+		}
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case slots <- struct{}{}:
+		}
+
+		result := checks.RunLocal(ctx, db, &target)
+		<-slots
+
+		if ctx.Err() != nil {
+			return
+		}
+
+		if result.Status.IsFailure() {
+			failures++
+		} else {
+			failures = 0
+		}
+
+		// Logged like updawg serve does, including unknown results
+		if result.Status != models.StatusUp {
+			logger.Printf("health check failed id=%s name=%s status=%s error=%s\n", target.Id(), target.DisplayName(), result.Status, result.Message)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case reports <- agent.NewReport(result):
+		}
+	}
+}
+
+// reportResults sends results to the server in batches, keeping them to
+// retry while it can't be reached.
+func reportResults(ctx context.Context, client *agent.Client, reports <-chan agent.Report) {
+	var pending []agent.Report
+
+	flush := func(ctx context.Context) {
+		if len(pending) == 0 {
+			return
+		}
+
+		if err := client.Report(ctx, pending); err != nil {
+			logger.Printf("failed to report results pending=%d error=%v\n", len(pending), err)
+			return
+		}
+
+		pending = nil
+	}
+
+	ticker := time.NewTicker(reportInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			flushCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), finalReportTimeout)
+			flush(flushCtx)
+			cancel()
+
+			return
+		case report := <-reports:
+			pending = append(pending, report)
+			if dropped := len(pending) - maxPendingReports; dropped > 0 {
+				logger.Printf("dropping unreported results count=%d\n", dropped)
+				pending = pending[dropped:]
+			}
+		case <-ticker.C:
+			flush(ctx)
+		}
+	}
+}
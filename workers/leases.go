@@ -0,0 +1,248 @@
+package workers
+
+import (
+	"context"
+	"hash/fnv"
+	"os"
+	"time"
+
+	"github.com/tehlordvortex/updawg/models"
+)
+
+// Several updawg serve instances can share a database. Each target is
+// assigned to one of the live instances by rendezvous hashing, so instances
+// joining or leaving only move their share of targets, and is only monitored
+// by the instance holding its lease.
+
+// join registers the instance so other instances rebalance targets with it.
+func (s *scheduler) join(ctx context.Context) {
+	hostname, _ := os.Hostname()
+	s.instance = &models.Instance{Hostname: hostname, Pid: os.Getpid()}
+
+	if err := s.instance.Save(ctx, s.db); err != nil {
+		logger.Fatalln(err)
+	}
+
+	logger.Printf("joined instance_id=%s lease_ttl=%s\n", s.instance.Id(), s.options.LeaseTtl)
+}
+
+// leave releases the instance's leases so other instances take over its
+// targets straight away rather than once the leases expire.
+func (s *scheduler) leave(ctx context.Context) {
+	if err := s.instance.Delete(ctx, s.db); err != nil {
+		logger.Printf("failed to release leases instance_id=%s error=%v\n", s.instance.Id(), err)
+		return
+	}
+
+	logger.Printf("left instance_id=%s\n", s.instance.Id())
+}
+
+// renewLeases heartbeats the instance, claims or releases targets so each is
+// held by the live instance it is assigned to, and starts or stops
+// monitoring them to match the leases held.
+func (s *scheduler) renewLeases(ctx context.Context) {
+	now := time.Now()
+	expiresAt := now.Add(s.options.LeaseTtl)
+
+	leased, err := s.rebalance(ctx, now, expiresAt)
+	if err != nil {
+		logger.Printf("failed to renew leases instance_id=%s error=%v\n", s.instance.Id(), err)
+
+		// Another instance may have taken over the targets once the leases
+		// have expired
+		if now.After(s.leaseExpiry) && len(s.leased) > 0 {
+			logger.Printf("leases expired, stopping monitoring for %d targets\n", len(s.leased))
+			for id := range s.leased {
+				if entry, exists := s.entries[id]; exists {
+					s.remove(entry)
+				}
+			}
+
+			s.leased = make(map[string]bool)
+		}
+
+		return
+	}
+
+	s.leaseExpiry = expiresAt
+
+	for id := range s.leased {
+		if leased[id] {
+			continue
+		}
+
+		if entry, exists := s.entries[id]; exists {
+			logger.Printf("handing over target id=%s name=%s\n", id, entry.target.DisplayName())
+			s.remove(entry)
+		}
+	}
+
+	var gained []string
+	for id := range leased {
+		if !s.leased[id] {
+			gained = append(gained, id)
+		}
+	}
+
+	s.leased = leased
+	if len(gained) == 0 {
+		return
+	}
+
+	targets, err := models.FindTargetsByIds(ctx, s.db, gained)
+	if err != nil {
+		logger.Printf("failed to load leased targets error=%v\n", err)
+		return
+	}
+
+	logger.Printf("starting monitoring for %d leased targets instance_id=%s\n", len(targets), s.instance.Id())
+	for _, target := range targets {
+		if _, exists := s.entries[target.Id()]; !exists {
+			s.add(&target)
+		}
+	}
+}
+
+// rebalance returns the ids of the targets the instance holds leases on,
+// after claiming those assigned to it and releasing those assigned to other
+// live instances.
+func (s *scheduler) rebalance(ctx context.Context, now, expiresAt time.Time) (map[string]bool, error) {
+	if err := s.instance.Heartbeat(ctx, s.db); err != nil {
+		return nil, err
+	}
+
+	// Instances which missed their heartbeats for a whole lease are dead,
+	// and would otherwise pile up with every restart
+	since := now.Add(-s.options.LeaseTtl)
+	if deleted, err := models.DeleteDeadInstances(ctx, s.db, since); err != nil {
+		return nil, err
+	} else if deleted > 0 {
+		logger.Printf("deleted %d dead instances instance_id=%s\n", deleted, s.instance.Id())
+	}
+
+	instances, err := models.FindLiveInstances(ctx, s.db, since)
+	if err != nil {
+		return nil, err
+	}
+
+	s.instances = s.instances[:0]
+	for _, instance := range instances {
+		s.instances = append(s.instances, instance.Id())
+	}
+
+	if err := models.RenewTargetLeases(ctx, s.db, s.instance.Id(), expiresAt); err != nil {
+		return nil, err
+	}
+
+	ids, err := models.FindAllTargetIds(ctx, s.db)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, id := range ids {
+		assigned := s.assignedInstance(id) == s.instance.Id()
+
+		if assigned && !s.leased[id] {
+			holder, err := models.FindTargetLeaseHolder(ctx, s.db, id)
+			if err != nil {
+				return nil, err
+			}
+
+			claimed, err := models.ClaimTargetLease(ctx, s.db, id, s.instance.Id(), expiresAt)
+			if err != nil {
+				return nil, err
+			}
+
+			// Another instance's lease can only be claimed once it has
+			// expired, so the instance stopped renewing it without
+			// handing the target over
+			if claimed && holder != "" && holder != s.instance.Id() {
+				logger.Printf("took over expired lease id=%s from_instance_id=%s instance_id=%s\n", id, holder, s.instance.Id())
+			}
+		} else if !assigned && s.leased[id] {
+			if err := models.ReleaseTargetLease(ctx, s.db, id, s.instance.Id()); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	leasedIds, err := models.FindTargetIdsLeasedBy(ctx, s.db, s.instance.Id())
+	if err != nil {
+		return nil, err
+	}
+
+	leased := make(map[string]bool, len(leasedIds))
+	for _, id := range leasedIds {
+		leased[id] = true
+	}
+
+	return leased, nil
+}
+
+// claim takes the lease on a new target if it's assigned to the instance,
+// reporting whether the instance holds it. Until leases are renewed again
+// there is no expiry to claim it until, so it's left to the next renewal.
+func (s *scheduler) claim(ctx context.Context, targetId string) bool {
+	if s.assignedInstance(targetId) != s.instance.Id() {
+		return false
+	}
+
+	if !time.Now().Before(s.leaseExpiry) {
+		logger.Printf("leases not renewed, not claiming lease id=%s\n", targetId)
+		return false
+	}
+
+	claimed, err := models.ClaimTargetLease(ctx, s.db, targetId, s.instance.Id(), s.leaseExpiry)
+	if err != nil {
+		logger.Printf("failed to claim lease id=%s error=%v\n", targetId, err)
+		return false
+	}
+
+	if claimed {
+		s.leased[targetId] = true
+	}
+
+	return claimed
+}
+
+// release gives up the lease on a deleted target.
+func (s *scheduler) release(ctx context.Context, targetId string) {
+	delete(s.leased, targetId)
+
+	if err := models.ReleaseTargetLease(ctx, s.db, targetId, s.instance.Id()); err != nil {
+		logger.Printf("failed to release lease id=%s error=%v\n", targetId, err)
+	}
+}
+
+// assignedInstance returns the live instance the target is assigned to,
+// the one with the highest hash of its id and the target's.
+func (s *scheduler) assignedInstance(targetId string) string {
+	var assigned string
+	var highest uint64
+
+	for _, instance := range s.instances {
+		h := fnv.New64a()
+		h.Write([]byte(instance))
+		h.Write([]byte(targetId))
+
+		if weight := mix64(h.Sum64()); assigned == "" || weight > highest {
+			assigned = instance
+			highest = weight
+		}
+	}
+
+	return assigned
+}
+
+// mix64 scrambles an FNV hash, whose high bits barely change between inputs
+// differing only near their end, so similar instance ids still get even
+// shares of the targets. It is MurmurHash3's finalizer.
+func mix64(h uint64) uint64 {
+	h ^= h >> 33
+	h *= 0xff51afd7ed558ccd
+	h ^= h >> 33
+	h *= 0xc4ceb9fe1a85ec53
+	h ^= h >> 33
+
+	return h
+}
@@ -0,0 +1,183 @@
+package workers
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"maps"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/tehlordvortex/updawg/models"
+)
+
+func TestAssignedInstance(t *testing.T) {
+	var targets []string
+	for i := 0; i < 1000; i++ {
+		targets = append(targets, fmt.Sprintf("target_%d", i))
+	}
+
+	assign := func(instances ...string) map[string]string {
+		s := &scheduler{instances: instances}
+
+		assigned := make(map[string]string, len(targets))
+		for _, target := range targets {
+			assigned[target] = s.assignedInstance(target)
+		}
+
+		return assigned
+	}
+
+	t.Run("no instances", func(t *testing.T) {
+		if got := assign()["target_0"]; got != "" {
+			t.Errorf("assignedInstance() = %q, want none", got)
+		}
+	})
+
+	t.Run("one instance", func(t *testing.T) {
+		for target, instance := range assign("instance_a") {
+			if instance != "instance_a" {
+				t.Fatalf("assignedInstance(%s) = %q, want instance_a", target, instance)
+			}
+		}
+	})
+
+	three := assign("instance_a", "instance_b", "instance_c")
+
+	t.Run("independent of instance order", func(t *testing.T) {
+		reordered := assign("instance_c", "instance_a", "instance_b")
+		for target, instance := range three {
+			if reordered[target] != instance {
+				t.Fatalf("assignedInstance(%s) = %q, then %q with instances reordered", target, instance, reordered[target])
+			}
+		}
+	})
+
+	t.Run("balanced", func(t *testing.T) {
+		counts := make(map[string]int)
+		for _, instance := range three {
+			counts[instance]++
+		}
+
+		for _, instance := range []string{"instance_a", "instance_b", "instance_c"} {
+			if counts[instance] < 250 || counts[instance] > 420 {
+				t.Errorf("%s was assigned %d of %d targets, want about a third", instance, counts[instance], len(targets))
+			}
+		}
+	})
+
+	t.Run("leaving only moves its own targets", func(t *testing.T) {
+		two := assign("instance_a", "instance_c")
+		for target, instance := range three {
+			if instance != "instance_b" && two[target] != instance {
+				t.Errorf("%s moved from %s to %s when instance_b left", target, instance, two[target])
+			}
+		}
+	})
+
+	t.Run("joining only takes targets for itself", func(t *testing.T) {
+		four := assign("instance_a", "instance_b", "instance_c", "instance_d")
+		for target, instance := range three {
+			if moved := four[target]; moved != instance && moved != "instance_d" {
+				t.Errorf("%s moved from %s to %s when instance_d joined", target, instance, moved)
+			}
+		}
+
+		if !slices.Contains(slices.Collect(maps.Values(four)), "instance_d") {
+			t.Errorf("instance_d wasn't assigned any targets")
+		}
+	})
+}
+
+// newTestLeaseScheduler returns a scheduler which has joined the cluster but
+// not renewed any leases yet.
+func newTestLeaseScheduler(t *testing.T, db *sql.DB) *scheduler {
+	t.Helper()
+
+	s := &scheduler{
+		db:      db,
+		options: Options{LeaseTtl: time.Minute},
+		entries: make(map[string]*targetEntry),
+		leased:  make(map[string]bool),
+	}
+	s.join(context.Background())
+
+	return s
+}
+
+func TestRebalanceDeletesDeadInstances(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDb(t)
+	s := newTestLeaseScheduler(t, db)
+
+	stale := time.Now().Add(-time.Hour).Unix()
+	if _, err := db.Exec("INSERT INTO instances (id, hostname, pid, heartbeat_at, created_at) VALUES ('instance_dead', 'gone', 1, ?, ?)", stale, stale); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	if _, err := s.rebalance(ctx, now, now.Add(s.options.LeaseTtl)); err != nil {
+		t.Fatal(err)
+	}
+
+	var count int
+	if err := db.QueryRow("SELECT count(*) FROM instances WHERE id = 'instance_dead'").Scan(&count); err != nil {
+		t.Fatal(err)
+	}
+	if count != 0 {
+		t.Errorf("dead instance wasn't deleted")
+	}
+
+	if !slices.Equal(s.instances, []string{s.instance.Id()}) {
+		t.Errorf("instances = %v, want only %s", s.instances, s.instance.Id())
+	}
+
+	t.Run("heartbeat registers a deleted instance again", func(t *testing.T) {
+		if _, err := db.Exec("DELETE FROM instances WHERE id = ?", s.instance.Id()); err != nil {
+			t.Fatal(err)
+		}
+
+		now := time.Now()
+		if _, err := s.rebalance(ctx, now, now.Add(s.options.LeaseTtl)); err != nil {
+			t.Fatal(err)
+		}
+
+		instances, err := models.FindLiveInstances(ctx, db, now.Add(-s.options.LeaseTtl))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(instances) != 1 || instances[0].Id() != s.instance.Id() {
+			t.Errorf("live instances = %v, want only %s", instances, s.instance.Id())
+		}
+	})
+}
+
+func TestClaimNeedsLeaseExpiry(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDb(t)
+	s := newTestLeaseScheduler(t, db)
+	s.instances = []string{s.instance.Id()}
+
+	target := newTestTarget(t, db, "http://example.test")
+
+	if s.claim(ctx, target.Id()) {
+		t.Errorf("claim() = true before leases were renewed, want false")
+	}
+
+	if holder, err := models.FindTargetLeaseHolder(ctx, db, target.Id()); err != nil {
+		t.Fatal(err)
+	} else if holder != "" {
+		t.Errorf("lease held by %s, want none", holder)
+	}
+
+	s.leaseExpiry = time.Now().Add(s.options.LeaseTtl)
+	if !s.claim(ctx, target.Id()) {
+		t.Errorf("claim() = false once leases were renewed, want true")
+	}
+
+	if _, err := models.ClaimTargetLease(ctx, db, target.Id(), s.instance.Id(), time.Time{}); err == nil {
+		t.Errorf("ClaimTargetLease() with a zero expiry didn't fail")
+	}
+}
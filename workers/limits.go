@@ -0,0 +1,85 @@
+package workers
+
+import (
+	"net"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/tehlordvortex/updawg/models"
+)
+
+// Kinds which don't connect to a host of their own, or whose uri doesn't
+// name one, and so aren't subject to per host limits.
+var kindsWithoutHost = []string{
+	models.TargetKindHeartbeat,
+	models.TargetKindComposite,
+	models.TargetKindTransaction,
+	models.TargetKindExec,
+	models.TargetKindSql,
+}
+
+// targetHost returns the host a target's checks connect to, or an empty
+// string if it has none.
+func targetHost(target *models.Target) string {
+	if slices.Contains(kindsWithoutHost, target.Kind) {
+		return ""
+	}
+
+	host := target.Uri
+	if strings.Contains(host, "://") {
+		u, err := url.Parse(host)
+		if err != nil {
+			return ""
+		}
+
+		host = u.Host
+	}
+
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	return strings.ToLower(strings.Trim(host, "[]"))
+}
+
+// tokenBucket allows rate events per second on average, with bursts of up to
+// a second's worth. A zero rate is unlimited.
+type tokenBucket struct {
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+func newTokenBucket(rate float64) *tokenBucket {
+	return &tokenBucket{rate: rate, tokens: max(rate, 1), last: time.Now()}
+}
+
+func (b *tokenBucket) refill(now time.Time) {
+	b.tokens = min(b.tokens+now.Sub(b.last).Seconds()*b.rate, max(b.rate, 1))
+	b.last = now
+}
+
+// wait returns how long until a token is available.
+func (b *tokenBucket) wait(now time.Time) time.Duration {
+	if b.rate <= 0 {
+		return 0
+	}
+
+	b.refill(now)
+	if b.tokens >= 1 {
+		return 0
+	}
+
+	return time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+}
+
+func (b *tokenBucket) take(now time.Time) {
+	if b.rate <= 0 {
+		return
+	}
+
+	b.refill(now)
+	b.tokens--
+}
@@ -0,0 +1,107 @@
+package workers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/tehlordvortex/updawg/models"
+)
+
+func TestTokenBucket(t *testing.T) {
+	start := time.Unix(1000, 0)
+	after := func(d time.Duration) time.Time { return start.Add(d) }
+
+	// Each step takes a token at its time if take is set, after checking
+	// the wait before it.
+	type step struct {
+		at   time.Time
+		want time.Duration
+		take bool
+	}
+
+	tests := []struct {
+		name  string
+		rate  float64
+		steps []step
+	}{
+		{
+			name: "unlimited",
+			rate: 0,
+			steps: []step{
+				{at: start, want: 0, take: true},
+				{at: start, want: 0, take: true},
+				{at: start, want: 0, take: true},
+			},
+		},
+		{
+			name: "bursts a second's worth",
+			rate: 2,
+			steps: []step{
+				{at: start, want: 0, take: true},
+				{at: start, want: 0, take: true},
+				{at: start, want: 500 * time.Millisecond},
+				{at: after(250 * time.Millisecond), want: 250 * time.Millisecond},
+				{at: after(500 * time.Millisecond), want: 0, take: true},
+				{at: after(500 * time.Millisecond), want: 500 * time.Millisecond},
+			},
+		},
+		{
+			name: "slower than once a second",
+			rate: 0.5,
+			steps: []step{
+				{at: start, want: 0, take: true},
+				{at: start, want: 2 * time.Second},
+				{at: after(time.Second), want: time.Second},
+				{at: after(2 * time.Second), want: 0, take: true},
+			},
+		},
+		{
+			name: "idle time doesn't build up",
+			rate: 2,
+			steps: []step{
+				{at: after(time.Minute), want: 0, take: true},
+				{at: after(time.Minute), want: 0, take: true},
+				{at: after(time.Minute), want: 500 * time.Millisecond},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := &tokenBucket{rate: tt.rate, tokens: max(tt.rate, 1), last: start}
+
+			for i, s := range tt.steps {
+				if got := b.wait(s.at); got != s.want {
+					t.Fatalf("step %d: wait() = %s, want %s", i, got, s.want)
+				}
+
+				if s.take {
+					b.take(s.at)
+				}
+			}
+		})
+	}
+}
+
+func TestTargetHost(t *testing.T) {
+	tests := []struct {
+		kind string
+		uri  string
+		want string
+	}{
+		{models.TargetKindHttp, "https://Example.com/path", "example.com"},
+		{models.TargetKindHttp, "http://example.com:8080", "example.com"},
+		{models.TargetKindHttp, "http://[::1]:8080/", "::1"},
+		{models.TargetKindSsh, "git.internal:2222", "git.internal"},
+		{models.TargetKindIcmp, "10.0.0.1", "10.0.0.1"},
+		{models.TargetKindExec, "/usr/lib/nagios/plugins/check_disk", ""},
+		{models.TargetKindHeartbeat, "", ""},
+	}
+
+	for _, tt := range tests {
+		target := models.Target{Kind: tt.kind, Uri: tt.uri}
+		if got := targetHost(&target); got != tt.want {
+			t.Errorf("targetHost(%s %s) = %q, want %q", tt.kind, tt.uri, got, tt.want)
+		}
+	}
+}
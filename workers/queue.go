@@ -0,0 +1,62 @@
+package workers
+
+import (
+	"container/heap"
+	"time"
+)
+
+// targetQueue orders entries by when they are next due. It implements
+// heap.Interface and should be used through push, remove and peek.
+type targetQueue []*targetEntry
+
+func (q targetQueue) Len() int { return len(q) }
+
+func (q targetQueue) Less(i, j int) bool { return q[i].nextRun.Before(q[j].nextRun) }
+
+func (q targetQueue) Swap(i, j int) {
+	q[i], q[j] = q[j], q[i]
+	q[i].index = i
+	q[j].index = j
+}
+
+func (q *targetQueue) Push(x any) {
+	entry := x.(*targetEntry)
+	entry.index = len(*q)
+	*q = append(*q, entry)
+}
+
+func (q *targetQueue) Pop() any {
+	old := *q
+	n := len(old)
+	entry := old[n-1]
+	old[n-1] = nil
+	entry.index = -1
+	*q = old[:n-1]
+
+	return entry
+}
+
+// push schedules entry to be due at nextRun, moving it if already queued.
+func (q *targetQueue) push(entry *targetEntry, nextRun time.Time) {
+	entry.nextRun = nextRun
+	if entry.index >= 0 {
+		heap.Fix(q, entry.index)
+	} else {
+		heap.Push(q, entry)
+	}
+}
+
+func (q *targetQueue) remove(entry *targetEntry) {
+	if entry.index >= 0 {
+		heap.Remove(q, entry.index)
+	}
+}
+
+// peek returns the entry due soonest without removing it.
+func (q targetQueue) peek() (*targetEntry, bool) {
+	if len(q) == 0 {
+		return nil, false
+	}
+
+	return q[0], true
+}
@@ -0,0 +1,149 @@
+package workers
+
+import (
+	"hash/fnv"
+	"math"
+	"time"
+
+	"github.com/tehlordvortex/updawg/models"
+	"github.com/tehlordvortex/updawg/schedule"
+)
+
+// splay returns a fixed offset within period for a target, so checks of
+// targets sharing a period are spread evenly across it rather than all
+// running at once, and keep the same offset across restarts.
+func splay(id string, period time.Duration) time.Duration {
+	if period <= 0 {
+		return 0
+	}
+
+	h := fnv.New64a()
+	h.Write([]byte(id))
+
+	return time.Duration(h.Sum64() % uint64(period))
+}
+
+// nextSlot returns the first time after now which is offset past a multiple
+// of period.
+func nextSlot(now time.Time, period, offset time.Duration) time.Time {
+	if period <= 0 {
+		return now
+	}
+
+	elapsed := (now.UnixNano() - int64(offset)) % int64(period)
+	if elapsed < 0 {
+		elapsed += int64(period)
+	}
+
+	return now.Add(period - time.Duration(elapsed))
+}
+
+const (
+	defaultRetryInterval = 5 * time.Second
+	defaultRetryBackoff  = 2
+)
+
+type retryConfig struct {
+	// RetryInterval is the number of seconds before re-checking a target
+	// which has just failed. Negative values disable re-checking early.
+	RetryInterval int64 `json:"retry_interval"`
+	// RetryBackoff multiplies the interval after each further failure.
+	RetryBackoff float64 `json:"retry_backoff"`
+	// RetryMaxInterval caps the interval, and defaults to the period. It
+	// may be longer than the period to check less often during long
+	// outages.
+	RetryMaxInterval int64 `json:"retry_max_interval"`
+}
+
+// retryDelay returns how long to wait before re-checking a target which has
+// failed the given number of checks in a row, or false if it should keep to
+// its schedule.
+func retryDelay(target *models.Target, failures int) (time.Duration, bool) {
+	var cfg retryConfig
+	if err := target.DecodeConfig(&cfg); err != nil {
+		logger.Printf("invalid retry config id=%s error=%v\n", target.Id(), err)
+	}
+
+	if failures <= 0 || cfg.RetryInterval < 0 {
+		return 0, false
+	}
+
+	interval := defaultRetryInterval
+	if cfg.RetryInterval > 0 {
+		interval = time.Duration(cfg.RetryInterval) * time.Second
+	}
+
+	backoff := float64(defaultRetryBackoff)
+	if cfg.RetryBackoff >= 1 {
+		backoff = cfg.RetryBackoff
+	}
+
+	maxInterval := time.Duration(target.Period) * time.Second
+	if cfg.RetryMaxInterval > 0 {
+		maxInterval = time.Duration(cfg.RetryMaxInterval) * time.Second
+	}
+
+	delay := float64(interval) * math.Pow(backoff, float64(failures-1))
+	if delay >= float64(maxInterval) {
+		return maxInterval, true
+	}
+
+	return time.Duration(delay), true
+}
+
+// nextCheck returns when the target is next due after now, following its
+// cron schedule or its splay offset into each period, within its active
+// hours. It returns the zero time if the target is never due.
+func nextCheck(target *models.Target, now time.Time) time.Time {
+	period := time.Duration(target.Period) * time.Second
+	offset := splay(target.Id(), period)
+	next := func(t time.Time) time.Time {
+		return nextSlot(t, period, offset)
+	}
+
+	s, err := target.ParseSchedule()
+	if err != nil {
+		logger.Printf("invalid schedule id=%s error=%v\n", target.Id(), err)
+		return next(now)
+	}
+
+	if s.Cron != nil {
+		next = s.Cron.Next
+	}
+
+	return schedule.Next(now.In(s.Location), next, s.ActiveHours)
+}
+
+// nextDue returns when a target which has failed the given number of checks
+// in a row is next due. Failing targets are re-checked sooner, backing off
+// the longer they fail, as long as it's within their active hours, in which
+// case retry is the delay before the re-check. Once the delay is longer than
+// the period, regular checks are held off until it has passed too.
+func nextDue(target *models.Target, failures int, now time.Time) (next time.Time, retry time.Duration) {
+	next = nextCheck(target, now)
+
+	delay, ok := retryDelay(target, failures)
+	if !ok {
+		return next, 0
+	}
+
+	sched, err := target.ParseSchedule()
+	if err != nil {
+		return next, 0
+	}
+
+	at := now.Add(delay)
+	if delay > time.Duration(target.Period)*time.Second {
+		if sched.IsActive(at) {
+			return at, delay
+		}
+
+		return nextCheck(target, at), 0
+	}
+
+	if (next.IsZero() || at.Before(next)) && sched.IsActive(at) {
+		return at, delay
+	}
+
+	return next, 0
+}
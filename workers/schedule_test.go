@@ -0,0 +1,156 @@
+package workers
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/tehlordvortex/updawg/models"
+)
+
+func TestSplay(t *testing.T) {
+	period := time.Minute
+
+	offsets := make(map[time.Duration]bool)
+	for i := 0; i < 100; i++ {
+		id := fmt.Sprintf("target_%d", i)
+
+		offset := splay(id, period)
+		if offset < 0 || offset >= period {
+			t.Fatalf("splay(%s) = %s, want within [0, %s)", id, offset, period)
+		}
+
+		if again := splay(id, period); again != offset {
+			t.Fatalf("splay(%s) = %s then %s, want the same offset", id, offset, again)
+		}
+
+		offsets[offset] = true
+	}
+
+	if len(offsets) < 90 {
+		t.Errorf("100 targets got %d distinct offsets, want them spread across the period", len(offsets))
+	}
+
+	if offset := splay("target_1", 0); offset != 0 {
+		t.Errorf("splay() with no period = %s, want 0", offset)
+	}
+}
+
+func TestNextSlot(t *testing.T) {
+	at := func(seconds int64) time.Time { return time.Unix(seconds, 0) }
+
+	tests := []struct {
+		name   string
+		now    time.Time
+		period time.Duration
+		offset time.Duration
+		want   time.Time
+	}{
+		{"before offset", at(100), time.Minute, 10 * time.Second, at(130)},
+		{"exactly on a slot", at(70), time.Minute, 10 * time.Second, at(130)},
+		{"just after a slot", at(71), time.Minute, 10 * time.Second, at(130)},
+		{"no offset", at(125), time.Minute, 0, at(180)},
+		{"before the epoch", at(-5), time.Minute, 10 * time.Second, at(10)},
+		{"no period", at(100), 0, 10 * time.Second, at(100)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := nextSlot(tt.now, tt.period, tt.offset); !got.Equal(tt.want) {
+				t.Errorf("nextSlot(%d, %s, %s) = %d, want %d", tt.now.Unix(), tt.period, tt.offset, got.Unix(), tt.want.Unix())
+			}
+		})
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	tests := []struct {
+		name      string
+		config    map[string]interface{}
+		failures  int
+		want      time.Duration
+		wantRetry bool
+	}{
+		{name: "not failing", failures: 0},
+		{name: "first failure", failures: 1, want: 5 * time.Second, wantRetry: true},
+		{name: "backs off", failures: 3, want: 20 * time.Second, wantRetry: true},
+		{name: "capped at the period", failures: 5, want: time.Minute, wantRetry: true},
+		{name: "disabled", config: map[string]interface{}{"retry_interval": -1}, failures: 1},
+		{name: "custom interval and backoff", config: map[string]interface{}{"retry_interval": 10, "retry_backoff": 3}, failures: 2, want: 30 * time.Second, wantRetry: true},
+		{name: "backoff below one is ignored", config: map[string]interface{}{"retry_backoff": 0.5}, failures: 2, want: 10 * time.Second, wantRetry: true},
+		{name: "max interval beyond the period", config: map[string]interface{}{"retry_max_interval": 300}, failures: 6, want: 160 * time.Second, wantRetry: true},
+		{name: "max interval reached", config: map[string]interface{}{"retry_max_interval": 300}, failures: 20, want: 300 * time.Second, wantRetry: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := models.Target{Period: 60}
+			target.SetConfig(tt.config)
+
+			got, retry := retryDelay(&target, tt.failures)
+			if got != tt.want || retry != tt.wantRetry {
+				t.Errorf("retryDelay(%d) = %s, %v, want %s, %v", tt.failures, got, retry, tt.want, tt.wantRetry)
+			}
+		})
+	}
+}
+
+func TestNextDue(t *testing.T) {
+	// A Monday
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	backoff := map[string]interface{}{"retry_max_interval": 300}
+
+	tests := []struct {
+		name      string
+		target    models.Target
+		config    map[string]interface{}
+		failures  int
+		wantRetry time.Duration
+		// wantFrom is how long after now the regular check is looked
+		// for when not retrying
+		wantFrom time.Duration
+	}{
+		{name: "not failing", target: models.Target{Period: 60}},
+		{name: "failing", target: models.Target{Period: 60}, failures: 1, wantRetry: 5 * time.Second},
+		{name: "retry after the next check", target: models.Target{Period: 60}, failures: 5},
+		{name: "backed off beyond the period", target: models.Target{Period: 60}, config: backoff, failures: 20, wantRetry: 300 * time.Second},
+		{
+			name:      "retry within active hours",
+			target:    models.Target{Period: 3600, ActiveHours: "mon-fri 09:00-17:00", Timezone: "UTC"},
+			failures:  1,
+			wantRetry: 5 * time.Second,
+		},
+		{
+			name:     "retry outside active hours",
+			target:   models.Target{Period: 3600, ActiveHours: "mon-fri 09:00-12:00", Timezone: "UTC"},
+			failures: 1,
+		},
+		{
+			name:     "backed off beyond active hours",
+			target:   models.Target{Period: 60, ActiveHours: "mon-fri 09:00-12:03", Timezone: "UTC"},
+			config:   backoff,
+			failures: 20,
+			wantFrom: 300 * time.Second,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.target.SetConfig(tt.config)
+
+			next, retry := nextDue(&tt.target, tt.failures, now)
+			if retry != tt.wantRetry {
+				t.Fatalf("nextDue() retry = %s, want %s", retry, tt.wantRetry)
+			}
+
+			want := now.Add(tt.wantRetry)
+			if tt.wantRetry == 0 {
+				want = nextCheck(&tt.target, now.Add(tt.wantFrom))
+			}
+
+			if !next.Equal(want) {
+				t.Errorf("nextDue() = %s, want %s", next, want)
+			}
+		})
+	}
+}
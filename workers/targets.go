@@ -3,143 +3,739 @@ package workers
 import (
 	"context"
 	"database/sql"
-	"net/http"
+	"slices"
+	"sync"
 	"time"
 
-	"github.com/tehlordvortex/updawg/config"
+	"github.com/tehlordvortex/updawg/checks"
 	"github.com/tehlordvortex/updawg/models"
 	"github.com/tehlordvortex/updawg/pubsub"
 )
 
-type targetState struct {
+// resumeRetryInterval is how long to wait before trying again to resume a
+// target whose pause has ended, if storing it failed.
+const resumeRetryInterval = 5 * time.Second
+
+// targetEntry is the scheduler's state for a single target. Entries are only
+// accessed from the scheduler goroutine; check workers are given a copy of
+// the target.
+type targetEntry struct {
+	ctx    context.Context
 	cancel context.CancelFunc
 	target *models.Target
+	// version is incremented whenever the target is reloaded so results of
+	// checks started before then don't overwrite it.
+	version   int
+	lastPing  time.Time
+	startedAt time.Time
+	// failures is the number of checks in a row which have failed.
+	failures int
+	// paused entries are only queued to be resumed at the target's
+	// PausedUntil.
+	paused bool
+
+	// nextRun is when the entry is next due and index its position in the
+	// queue, -1 when it isn't queued.
+	nextRun time.Time
+	index   int
+
+	running bool
+	ready   bool
+	// limitedAt is when a limit first held back the entry while a worker
+	// was free, to record how long limits delayed its check. Time spent
+	// waiting for a worker isn't counted.
+	limitedAt time.Time
+	// checkDue is set when a check should run as soon as a worker is free,
+	// and results holds heartbeat results waiting to be recorded in order.
+	checkDue bool
+	results  []models.CheckResult
+	// requests are the ids of check requests the next check answers.
+	requests []string
 }
 
-func runTargetsWorker(ctx context.Context, db *sql.DB) {
-	targetsPubSub, unsub, err := pubsub.SubscribeMany(ctx, []string{models.TargetCreatedTopic, models.TargetDeletedTopic})
-	if err != nil {
-		logger.Fatalln(err)
+// checkJob is sent to a check worker, which runs a check against target or
+// records result if set.
+type checkJob struct {
+	ctx     context.Context
+	target  models.Target
+	version int
+	result  *models.CheckResult
+	// host is the host the check counts against, if it is limited.
+	host string
+	// lag is how long the check was held back by limits.
+	lag      time.Duration
+	requests []string
+}
+
+// checkDone is sent back to the scheduler when a job finishes, with the
+// target's status updated.
+type checkDone struct {
+	target  models.Target
+	version int
+	host    string
+}
+
+// scheduler keeps every target in a queue ordered by when it is next due,
+// and dispatches due checks to a fixed size pool of check workers.
+type scheduler struct {
+	// ctx is the parent of every check's context. It outlives the context
+	// given to runScheduler so checks can finish when shutting down.
+	ctx     context.Context
+	db      *sql.DB
+	options Options
+	entries map[string]*targetEntry
+	queue   targetQueue
+	// pending holds entries with work waiting for a free worker, in the
+	// order it became due.
+	pending []*targetEntry
+	jobs    chan checkJob
+	done    chan checkDone
+	// inFlight is the number of jobs sent to workers which haven't finished
+	inFlight int
+
+	// Checks running against each host, and the rate limits of all checks
+	// and of each host
+	hostRunning map[string]int
+	rateLimit   *tokenBucket
+	hostLimits  map[string]*tokenBucket
+
+	// instance is this process's registration, instances the ids of the
+	// live instances targets are shared between, and leased the targets
+	// this instance holds leases on until leaseExpiry
+	instance    *models.Instance
+	instances   []string
+	leased      map[string]bool
+	leaseExpiry time.Time
+}
+
+func runTargetsWorker(ctx context.Context, db *sql.DB, options Options) {
+	topics := []string{
+		models.TargetCreatedTopic,
+		models.TargetDeletedTopic,
+		models.TargetUpdatedTopic,
+		models.TargetPingedTopic,
+		models.TargetStatusChangedTopic,
+		models.TargetCheckRequestedTopic,
+		models.TargetPausedTopic,
+		models.TargetResumedTopic,
+		models.InstanceJoinedTopic,
+		models.InstanceLeftTopic,
 	}
-	defer unsub()
 
-	targets, err := models.FindAllActiveTargets(ctx, db)
+	messages, unsub, err := pubsub.SubscribeMany(ctx, topics)
 	if err != nil {
 		logger.Fatalln(err)
 	}
 
-	states := make(map[string]targetState)
-	logger.Printf("starting monitoring for %d targets\n", len(targets))
+	runScheduler(ctx, db, options, checks.Run, messages, unsub)
+}
 
-	for _, target := range targets {
-		targetCtx, cancel := context.WithCancel(ctx)
-		state := targetState{cancel: cancel, target: &target}
-		states[target.Id()] = state
+// runScheduler monitors targets using check, handling messages until ctx is
+// done, then unsubscribes and waits for the checks in progress.
+func runScheduler(ctx context.Context, db *sql.DB, options Options, check checks.Checker, messages <-chan pubsub.Message, unsub context.CancelFunc) {
+	checksCtx, cancelChecks := context.WithCancel(context.WithoutCancel(ctx))
+	defer cancelChecks()
 
-		go runTargetWorker(targetCtx, db, &state)
+	s := &scheduler{
+		ctx:         checksCtx,
+		db:          db,
+		options:     options,
+		entries:     make(map[string]*targetEntry),
+		jobs:        make(chan checkJob),
+		done:        make(chan checkDone),
+		hostRunning: make(map[string]int),
+		rateLimit:   newTokenBucket(options.Rate),
+		hostLimits:  make(map[string]*tokenBucket),
+		leased:      make(map[string]bool),
 	}
 
+	var workers sync.WaitGroup
+	for i := 0; i < options.PoolSize; i++ {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			runCheckWorker(checksCtx, db, check, s.jobs, s.done)
+		}()
+	}
+
+	logger.Printf("starting monitoring workers=%d host_concurrency=%d rate=%g host_rate=%g\n", options.PoolSize, options.HostConcurrency, options.Rate, options.HostRate)
+
+	// Monitor the targets this instance is assigned, including paused
+	// targets so they can be resumed automatically
+	s.join(ctx)
+	s.renewLeases(ctx)
+
+	leaseTicker := time.NewTicker(options.LeaseTtl / 3)
+	defer leaseTicker.Stop()
+
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+
 	for {
+		// Only offer a job while there is one, so the scheduler keeps
+		// handling messages while every worker is busy
+		var jobs chan<- checkJob
+		job, entry, wake, ok := s.nextJob(time.Now())
+		if ok {
+			jobs = s.jobs
+		}
+
+		// Wake for the next due entry, or when a rate limited check may run
+		if next, ok := s.queue.peek(); ok && (wake.IsZero() || next.nextRun.Before(wake)) {
+			wake = next.nextRun
+		}
+
+		var timerC <-chan time.Time
+		if !wake.IsZero() {
+			timer.Reset(max(time.Until(wake), 0))
+			timerC = timer.C
+		} else {
+			timer.Stop()
+		}
+
 		select {
 		case <-ctx.Done():
+			s.drain(messages, unsub, cancelChecks)
+			close(s.jobs)
+			workers.Wait()
+			s.leave(context.WithoutCancel(ctx))
 			return
-		case message := <-targetsPubSub:
-			switch message.Topic {
-			case models.TargetCreatedTopic:
-				_, exists := states[message.Msg]
-				if exists {
-					continue
-				}
-
-				target, err := models.FindTargetById(ctx, db, message.Msg)
-				if err != nil {
-					logger.Printf("failed to load target %s: %v\n", message.Msg, err)
-					continue
-				}
-
-				targetCtx, cancel := context.WithCancel(ctx)
-				state := targetState{cancel: cancel, target: &target}
-				states[target.Id()] = state
-
-				logger.Printf("starting monitoring for new target id=%s name=%s\n", target.Id(), target.DisplayName())
-				go runTargetWorker(targetCtx, db, &state)
-			case models.TargetDeletedTopic:
-				state, exists := states[message.Msg]
-				if !exists {
-					continue
-				}
-
-				logger.Printf("stopping monitoring for deleted target id=%s\n", message.Msg)
-				state.cancel()
-				delete(states, message.Msg)
-			}
+		case message := <-messages:
+			s.handleMessage(ctx, message)
+		case <-leaseTicker.C:
+			s.renewLeases(ctx)
+		case <-timerC:
+			s.runDue(ctx)
+		case jobs <- job:
+			s.started(entry, job)
+		case done := <-s.done:
+			s.finished(ctx, done)
 		}
 	}
 }
 
-func runTargetWorker(ctx context.Context, db *sql.DB, state *targetState) {
-	defer state.cancel()
+func (s *scheduler) add(target *models.Target) *targetEntry {
+	entryCtx, cancel := context.WithCancel(s.ctx)
+	entry := &targetEntry{ctx: entryCtx, cancel: cancel, target: target, index: -1}
+	s.entries[target.Id()] = entry
 
-	targetPubSub, unsub, err := pubsub.Subscribe(ctx, models.TargetUpdatedTopic)
-	if err != nil {
-		logger.Fatalln("pubsub.Sub failed:", err)
+	if !target.Enabled() {
+		s.pause(entry)
+		return entry
 	}
-	defer unsub()
 
-	var timer <-chan time.Time
-	next := func() {
-		timer = time.After(time.Duration(state.target.Period) * time.Second)
+	if target.Kind == models.TargetKindComposite {
+		s.markDue(entry)
 	}
-	next()
 
-	for {
+	s.schedule(entry)
+
+	return entry
+}
+
+// pause stops monitoring the entry's target, cancelling any check in
+// progress, and queues it to be resumed if it is paused until a set time.
+func (s *scheduler) pause(entry *targetEntry) {
+	entry.cancel()
+	entry.ctx, entry.cancel = context.WithCancel(s.ctx)
+
+	entry.paused = true
+	entry.checkDue = false
+	entry.results = nil
+	entry.requests = nil
+	entry.failures = 0
+
+	if until := entry.target.PausedUntil(); !until.IsZero() {
+		s.queue.push(entry, until)
+	} else {
+		s.queue.remove(entry)
+	}
+}
+
+func (s *scheduler) resume(entry *targetEntry) {
+	entry.paused = false
+	s.queue.remove(entry)
+	s.schedule(entry)
+	s.checkNow(entry)
+}
+
+// checkNow checks the target straight away rather than waiting for it to be
+// due, if it's within its active hours.
+func (s *scheduler) checkNow(entry *targetEntry) {
+	if entry.target.Kind == models.TargetKindHeartbeat {
+		return
+	}
+
+	if sched, err := entry.target.ParseSchedule(); err == nil && sched.IsActive(time.Now()) {
+		s.markDue(entry)
+	}
+}
+
+func (s *scheduler) remove(entry *targetEntry) {
+	entry.cancel()
+	s.queue.remove(entry)
+	delete(s.entries, entry.target.Id())
+}
+
+// schedule queues the entry's next check or heartbeat deadline.
+func (s *scheduler) schedule(entry *targetEntry) {
+	switch entry.target.Kind {
+	case models.TargetKindHeartbeat:
+		s.queue.push(entry, time.Now().Add(checks.HeartbeatDeadline(entry.target)))
+	case models.TargetKindComposite:
+		// Composites are evaluated when a member's status changes
+		s.queue.remove(entry)
+	default:
+		next, retry := nextDue(entry.target, entry.failures, time.Now())
+		if retry > 0 {
+			logger.Printf("rechecking failing target id=%s failures=%d in=%s\n", entry.target.Id(), entry.failures, retry)
+		}
+
+		if next.IsZero() {
+			logger.Printf("target is never due id=%s\n", entry.target.Id())
+			s.queue.remove(entry)
+			return
+		}
+
+		s.queue.push(entry, next)
+	}
+}
+
+func (s *scheduler) markDue(entry *targetEntry) {
+	entry.checkDue = true
+	s.markReady(entry)
+}
+
+func (s *scheduler) queueResult(entry *targetEntry, result models.CheckResult) {
+	entry.results = append(entry.results, result)
+	s.markReady(entry)
+}
+
+func (s *scheduler) markReady(entry *targetEntry) {
+	if entry.ready || entry.running {
+		return
+	}
+
+	entry.ready = true
+	entry.limitedAt = time.Time{}
+	s.pending = append(s.pending, entry)
+}
+
+// drain stops scheduling checks and waits for those in progress to finish,
+// cancelling them if they're still running after the shutdown timeout.
+func (s *scheduler) drain(messages <-chan pubsub.Message, unsub context.CancelFunc, cancelChecks context.CancelFunc) {
+	logger.Printf("stopping monitoring in_flight=%d timeout=%s\n", s.inFlight, s.options.ShutdownTimeout)
+
+	// Keep receiving messages until unsubscribed, as delivery blocks
+	unsubscribed := make(chan struct{})
+	go func() {
+		unsub()
+		close(unsubscribed)
+	}()
+
+	deadline := time.NewTimer(s.options.ShutdownTimeout)
+	defer deadline.Stop()
+
+	for s.inFlight > 0 || messages != nil {
 		select {
-		case <-ctx.Done():
+		case <-messages:
+		case <-unsubscribed:
+			messages = nil
+			unsubscribed = nil
+		case done := <-s.done:
+			s.finished(s.ctx, done)
+		case <-deadline.C:
+			if s.inFlight > 0 {
+				logger.Printf("shutdown timeout reached, cancelling checks in_flight=%d\n", s.inFlight)
+			}
+
+			cancelChecks()
 			return
-		case message := <-targetPubSub:
-			if message.Msg != state.target.Id() {
+		}
+	}
+
+	logger.Println("stopped monitoring")
+}
+
+// runDue handles every entry which has become due.
+func (s *scheduler) runDue(ctx context.Context) {
+	now := time.Now()
+
+	for {
+		entry, ok := s.queue.peek()
+		if !ok || entry.nextRun.After(now) {
+			return
+		}
+
+		s.queue.remove(entry)
+
+		if entry.paused {
+			if err := entry.target.Resume(ctx, s.db); err != nil {
+				logger.Printf("failed to resume target id=%s error=%v retry_in=%s\n", entry.target.Id(), err, resumeRetryInterval)
+				s.queue.push(entry, now.Add(resumeRetryInterval))
 				continue
 			}
 
-			if err := state.target.Reload(ctx, db); err != nil {
-				if err == sql.ErrNoRows {
-					return
-				}
+			logger.Printf("resuming monitoring for target id=%s name=%s\n", entry.target.Id(), entry.target.DisplayName())
+			s.resume(entry)
+		} else if entry.target.Kind == models.TargetKindHeartbeat {
+			// Missed pings only count within the target's active hours
+			if sched, err := entry.target.ParseSchedule(); err != nil || sched.IsActive(now) {
+				s.queueResult(entry, checks.HeartbeatMissedResult(entry.target, entry.lastPing))
+			}
 
-				logger.Printf("failed to reload target: %v id=%", err, state.target.Id())
-				return
+			s.schedule(entry)
+		} else {
+			s.markDue(entry)
+		}
+	}
+}
+
+// nextJob returns the job for the first pending entry which isn't held back
+// by a limit, dropping entries which were deleted while waiting. If every
+// entry is rate limited, wake is when the first may run.
+func (s *scheduler) nextJob(now time.Time) (job checkJob, entry *targetEntry, wake time.Time, ok bool) {
+	s.pending = slices.DeleteFunc(s.pending, func(entry *targetEntry) bool {
+		if s.entries[entry.target.Id()] != entry || entry.paused {
+			entry.ready = false
+			return true
+		}
+
+		return false
+	})
+
+	for _, entry := range s.pending {
+		job := checkJob{ctx: entry.ctx, target: *entry.target, version: entry.version}
+
+		// Recording heartbeat results doesn't contact the host
+		if len(entry.results) > 0 {
+			result := entry.results[0]
+			job.result = &result
+
+			return job, entry, time.Time{}, true
+		}
+
+		job.host = targetHost(entry.target)
+		wait := s.limitWait(job.host, now)
+		if wait != 0 && entry.limitedAt.IsZero() {
+			entry.limitedAt = now
+		}
+
+		if wait > 0 {
+			if retry := now.Add(wait); wake.IsZero() || retry.Before(wake) {
+				wake = retry
 			}
-		case <-timer:
-			req, err := http.NewRequestWithContext(ctx, state.target.Method, state.target.Uri, nil)
-			if err != nil {
-				if err == context.Canceled {
-					return
-				}
-				logger.Printf("health check failed id=%s name=%s error=%v\n", state.target.Id(), state.target.DisplayName(), err)
 
-				next()
-				continue
+			continue
+		} else if wait < 0 {
+			continue
+		}
+
+		if !entry.limitedAt.IsZero() {
+			job.lag = now.Sub(entry.limitedAt)
+		}
+		job.requests = entry.requests
+		return job, entry, time.Time{}, true
+	}
+
+	return checkJob{}, nil, wake, false
+}
+
+// limitWait returns how long until a check against host may start under the
+// rate limits, or a negative duration if it must wait for a running check
+// against the host to finish.
+func (s *scheduler) limitWait(host string, now time.Time) time.Duration {
+	if host != "" && s.options.HostConcurrency > 0 && s.hostRunning[host] >= s.options.HostConcurrency {
+		return -1
+	}
+
+	wait := s.rateLimit.wait(now)
+	if host != "" && s.options.HostRate > 0 {
+		wait = max(wait, s.hostLimit(host).wait(now))
+	}
+
+	return wait
+}
+
+func (s *scheduler) hostLimit(host string) *tokenBucket {
+	bucket, ok := s.hostLimits[host]
+	if !ok {
+		bucket = newTokenBucket(s.options.HostRate)
+		s.hostLimits[host] = bucket
+	}
+
+	return bucket
+}
+
+func (s *scheduler) started(entry *targetEntry, job checkJob) {
+	s.pending = slices.DeleteFunc(s.pending, func(e *targetEntry) bool {
+		return e == entry
+	})
+	entry.ready = false
+	entry.running = true
+	s.inFlight++
+
+	if job.result == nil {
+		now := time.Now()
+		s.rateLimit.take(now)
+		if job.host != "" {
+			s.hostRunning[job.host]++
+			if s.options.HostRate > 0 {
+				s.hostLimit(job.host).take(now)
 			}
+		}
 
-			res, err := http.DefaultClient.Do(req)
-			if err != nil {
-				if err == context.Canceled {
-					return
-				}
-				logger.Printf("health check failed id=%s name=%s error=%v\n", state.target.Id(), state.target.DisplayName(), err)
+		if job.lag >= time.Second {
+			logger.Printf("check delayed by limits id=%s host=%s lag=%s\n", entry.target.Id(), job.host, job.lag.Round(time.Millisecond))
+		}
+	}
 
-				next()
-				continue
+	if len(entry.results) > 0 {
+		entry.results = entry.results[1:]
+	} else {
+		entry.checkDue = false
+		entry.requests = nil
+	}
+}
+
+func (s *scheduler) finished(ctx context.Context, done checkDone) {
+	s.inFlight--
+
+	if done.host != "" {
+		if s.hostRunning[done.host]--; s.hostRunning[done.host] <= 0 {
+			delete(s.hostRunning, done.host)
+		}
+	}
+
+	entry, ok := s.entries[done.target.Id()]
+	if !ok {
+		return
+	}
+
+	entry.running = false
+
+	// Keep the status set by the worker, unless the target was reloaded in
+	// the meantime and needs reloading again to pick it up
+	if done.version == entry.version {
+		target := done.target
+		entry.target = &target
+	} else if err := entry.target.Reload(ctx, s.db); err != nil {
+		logger.Printf("failed to reload target id=%s error=%v\n", entry.target.Id(), err)
+	}
+
+	if entry.paused {
+		return
+	}
+
+	if entry.target.Status().IsFailure() {
+		entry.failures++
+	} else {
+		entry.failures = 0
+	}
+
+	if entry.checkDue || len(entry.results) > 0 {
+		s.markReady(entry)
+	} else if entry.target.Kind != models.TargetKindHeartbeat && entry.index < 0 {
+		s.schedule(entry)
+	}
+}
+
+func (s *scheduler) handleMessage(ctx context.Context, message pubsub.Message) {
+	switch message.Topic {
+	case models.TargetCreatedTopic:
+		// The target may already have been picked up when renewing leases
+		entry, exists := s.entries[message.Msg]
+		if !exists {
+			if !s.claim(ctx, message.Msg) {
+				return
 			}
 
-			if res.StatusCode != config.DefaultResponseCode {
-				logger.Printf("health check failed id=%s name=%s status=%d\n", state.target.Id(), state.target.DisplayName(), res.StatusCode)
+			target, err := models.FindTargetById(ctx, s.db, message.Msg)
+			if err != nil {
+				logger.Printf("failed to load target %s: %v\n", message.Msg, err)
+				return
+			}
 
-				next()
-				continue
+			logger.Printf("starting monitoring for new target id=%s name=%s\n", target.Id(), target.DisplayName())
+			entry = s.add(&target)
+		}
+
+		// Check new targets straight away rather than waiting for their slot
+		if !entry.paused {
+			s.checkNow(entry)
+		}
+	case models.TargetDeletedTopic:
+		entry, exists := s.entries[message.Msg]
+		if !exists {
+			return
+		}
+
+		logger.Printf("stopping monitoring for deleted target id=%s\n", message.Msg)
+		s.remove(entry)
+		s.release(ctx, message.Msg)
+	case models.TargetUpdatedTopic:
+		entry, exists := s.entries[message.Msg]
+		if !exists {
+			return
+		}
+
+		previous := *entry.target
+		if err := entry.target.Reload(ctx, s.db); err != nil {
+			logger.Printf("failed to reload target: %v id=%s\n", err, message.Msg)
+			s.remove(entry)
+			return
+		}
+
+		entry.version++
+		if entry.paused {
+			return
+		}
+
+		if isRescheduled(&previous, entry.target) {
+			s.schedule(entry)
+		}
+
+		if entry.target.Kind == models.TargetKindComposite {
+			s.markDue(entry)
+		}
+	case models.TargetPausedTopic, models.TargetResumedTopic:
+		entry, exists := s.entries[message.Msg]
+		if !exists {
+			return
+		}
+
+		if err := entry.target.Reload(ctx, s.db); err != nil {
+			logger.Printf("failed to reload target: %v id=%s\n", err, message.Msg)
+			return
+		}
+
+		entry.version++
+
+		if !entry.target.Enabled() {
+			logger.Printf("pausing monitoring for target id=%s name=%s\n", entry.target.Id(), entry.target.DisplayName())
+			s.pause(entry)
+		} else if entry.paused {
+			logger.Printf("resuming monitoring for target id=%s name=%s\n", entry.target.Id(), entry.target.DisplayName())
+			s.resume(entry)
+		}
+	case models.TargetCheckRequestedTopic:
+		request, err := models.DecodeCheckRequest(message.Msg)
+		if err != nil {
+			logger.Printf("invalid check request: %v\n", err)
+			return
+		}
+
+		entry, exists := s.entries[request.TargetId]
+		if !exists || entry.paused || entry.target.Kind == models.TargetKindHeartbeat {
+			return
+		}
+
+		logger.Printf("check requested id=%s request_id=%s\n", request.TargetId, request.Id)
+		entry.requests = append(entry.requests, request.Id)
+		s.markDue(entry)
+	case models.TargetStatusChangedTopic:
+		for _, entry := range s.entries {
+			if !entry.paused && entry.target.Kind == models.TargetKindComposite && slices.Contains(checks.CompositeMembers(entry.target), message.Msg) {
+				s.markDue(entry)
 			}
+		}
+	case models.InstanceJoinedTopic, models.InstanceLeftTopic:
+		if message.Msg != s.instance.Id() {
+			s.renewLeases(ctx)
+		}
+	case models.TargetPingedTopic:
+		ping, err := models.DecodePing(message.Msg)
+		if err != nil {
+			logger.Printf("invalid ping: %v\n", err)
+			return
+		}
+
+		entry, exists := s.entries[ping.TargetId]
+		if !exists || entry.paused || entry.target.Kind != models.TargetKindHeartbeat {
+			return
+		}
+
+		// A start only records when the run began, so a run which starts
+		// and then hangs is still missed at the deadline. Only a success
+		// pushes the deadline back.
+		entry.lastPing = time.Now()
+		if ping.Signal == models.PingSignalStart {
+			entry.startedAt = entry.lastPing
+		} else if result, ok := checks.HeartbeatPingResult(entry.target, ping, entry.startedAt); ok {
+			entry.startedAt = time.Time{}
+			s.queueResult(entry, result)
+		}
+
+		if ping.Signal == models.PingSignalSuccess {
+			s.schedule(entry)
+		}
+	}
+}
 
-			next()
+// isRescheduled reports whether an update changed when the target is due.
+func isRescheduled(previous, target *models.Target) bool {
+	return previous.Kind != target.Kind ||
+		previous.Period != target.Period ||
+		previous.Schedule != target.Schedule ||
+		previous.ActiveHours != target.ActiveHours ||
+		previous.Timezone != target.Timezone
+}
+
+// runCheckWorker runs jobs from the scheduler until jobs is closed or ctx is
+// done.
+func runCheckWorker(ctx context.Context, db *sql.DB, check checks.Checker, jobs <-chan checkJob, done chan<- checkDone) {
+	for job := range jobs {
+		if job.result != nil {
+			recordResult(job.ctx, db, &job.target, *job.result)
+		} else {
+			runCheck(job.ctx, db, check, &job.target, job.lag, job.requests)
 		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case done <- checkDone{target: job.target, version: job.version, host: job.host}:
+		}
+	}
+}
+
+// runCheck checks the target with check and records the result, along with how long
+// limits held the check back and the check requests it answers.
+func runCheck(ctx context.Context, db *sql.DB, check checks.Checker, target *models.Target, lag time.Duration, requests []string) {
+	result := check(ctx, db, target)
+	if ctx.Err() != nil {
+		return
+	}
+
+	if result.Data == nil {
+		result.Data = make(map[string]interface{})
+	}
+	result.Data["lag_ms"] = float64(lag.Microseconds()) / 1000
+	if len(requests) > 0 {
+		result.Data["request_ids"] = requests
+	}
+
+	recordResult(ctx, db, target, result)
+}
+
+func recordResult(ctx context.Context, db *sql.DB, target *models.Target, result models.CheckResult) {
+	result = checks.ApplyDependencies(ctx, db, target, result)
+
+	if err := result.Save(ctx, db); err != nil {
+		logger.Printf("failed to save check result id=%s error=%v\n", target.Id(), err)
+	}
+
+	// Unknown results are logged too, as they're usually a broken check
+	// rather than a healthy target; unreachable ones are the parent's failure
+	if result.Status != models.StatusUp && result.Status != models.StatusUnreachable {
+		logger.Printf("health check failed id=%s name=%s status=%s error=%s\n", target.Id(), target.DisplayName(), result.Status, result.Message)
+	}
+
+	previous := target.Status()
+	changed, err := target.UpdateStatus(ctx, db, result.Status)
+	if err != nil {
+		logger.Printf("failed to update status id=%s error=%v\n", target.Id(), err)
+	} else if changed {
+		logger.Printf("status changed id=%s name=%s from=%s to=%s\n", target.Id(), target.DisplayName(), previous, result.Status)
 	}
 }
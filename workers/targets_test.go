@@ -0,0 +1,464 @@
+package workers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tehlordvortex/updawg/checks"
+	"github.com/tehlordvortex/updawg/database"
+	"github.com/tehlordvortex/updawg/models"
+	"github.com/tehlordvortex/updawg/pubsub"
+)
+
+// newTestDb returns a migrated database which is removed after the test.
+func newTestDb(t *testing.T) *sql.DB {
+	t.Helper()
+	t.Setenv("UPDAWG_DB", filepath.Join(t.TempDir(), "test.db"))
+
+	db := database.Connect(context.Background())
+	t.Cleanup(func() { database.Close(db) })
+
+	return db
+}
+
+// newTestTarget saves an hourly http target with the given uri.
+func newTestTarget(t *testing.T, db *sql.DB, uri string) models.Target {
+	t.Helper()
+
+	target := models.Target{Kind: models.TargetKindHttp, Uri: uri, Period: 3600}
+	if err := target.Save(context.Background(), db); err != nil {
+		t.Fatal(err)
+	}
+
+	return target
+}
+
+// fakeChecker counts the checks it runs and the most running at once. Checks
+// are held until release is closed, if it is set.
+type fakeChecker struct {
+	release chan struct{}
+
+	mu         sync.Mutex
+	calls      map[string]int
+	running    int
+	maxRunning int
+}
+
+func newFakeChecker(hold bool) *fakeChecker {
+	f := &fakeChecker{calls: make(map[string]int)}
+	if hold {
+		f.release = make(chan struct{})
+	}
+
+	return f
+}
+
+func (f *fakeChecker) check(ctx context.Context, qe models.QueryExecutor, target *models.Target) models.CheckResult {
+	f.mu.Lock()
+	f.calls[target.Id()]++
+	f.running++
+	f.maxRunning = max(f.maxRunning, f.running)
+	f.mu.Unlock()
+
+	defer func() {
+		f.mu.Lock()
+		f.running--
+		f.mu.Unlock()
+	}()
+
+	if f.release != nil {
+		select {
+		case <-f.release:
+		case <-ctx.Done():
+			return models.CheckResult{TargetId: target.Id(), Status: models.StatusUnknown, Message: "cancelled"}
+		}
+	}
+
+	return models.CheckResult{TargetId: target.Id(), Status: models.StatusUp, Message: "ok"}
+}
+
+// stats returns the number of checks run, of the target if id is set, and
+// how many are running.
+func (f *fakeChecker) stats(id string) (calls, running int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if id != "" {
+		return f.calls[id], f.running
+	}
+
+	for _, n := range f.calls {
+		calls += n
+	}
+
+	return calls, f.running
+}
+
+// startScheduler runs a scheduler using check, returning the channel to send
+// it messages on and a function which stops it, returning once it has.
+func startScheduler(t *testing.T, db *sql.DB, options Options, check checks.Checker) (chan<- pubsub.Message, func()) {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	messages := make(chan pubsub.Message)
+	stopped := make(chan struct{})
+
+	options.PoolSize = max(options.PoolSize, 1)
+	options.LeaseTtl = time.Minute
+
+	go func() {
+		defer close(stopped)
+		runScheduler(ctx, db, options, check, messages, func() {})
+	}()
+
+	stop := sync.OnceFunc(func() {
+		cancel()
+		<-stopped
+	})
+	t.Cleanup(stop)
+
+	return messages, stop
+}
+
+// waitFor fails the test if cond isn't true within a few seconds.
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+
+	for deadline := time.Now().Add(5 * time.Second); !cond(); {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestSchedulerRunsDueTargetOnce(t *testing.T) {
+	db := newTestDb(t)
+	checker := newFakeChecker(false)
+	target := newTestTarget(t, db, "http://example.test")
+
+	messages, _ := startScheduler(t, db, Options{PoolSize: 2}, checker.check)
+
+	// New targets are due straight away, then not for another period
+	messages <- pubsub.Message{Topic: models.TargetCreatedTopic, Msg: target.Id()}
+	waitFor(t, "the check", func() bool {
+		calls, _ := checker.stats(target.Id())
+		return calls > 0
+	})
+
+	time.Sleep(100 * time.Millisecond)
+	if calls, _ := checker.stats(target.Id()); calls != 1 {
+		t.Errorf("target was checked %d times, want once", calls)
+	}
+
+	waitFor(t, "the status to be recorded", func() bool {
+		if err := target.Reload(context.Background(), db); err != nil {
+			t.Fatal(err)
+		}
+
+		return target.Status() == models.StatusUp
+	})
+}
+
+func TestSchedulerConcurrency(t *testing.T) {
+	tests := []struct {
+		name    string
+		options Options
+		uris    []string
+		want    int
+	}{
+		{
+			name:    "bounded by the pool",
+			options: Options{PoolSize: 2},
+			uris:    []string{"http://a.test", "http://b.test", "http://c.test", "http://d.test"},
+			want:    2,
+		},
+		{
+			name:    "bounded by the host limit",
+			options: Options{PoolSize: 4, HostConcurrency: 1},
+			uris:    []string{"http://example.test/a", "http://example.test/b", "http://example.test/c"},
+			want:    1,
+		},
+		{
+			name:    "host limit only counts the same host",
+			options: Options{PoolSize: 4, HostConcurrency: 1},
+			uris:    []string{"http://example.test/a", "http://example.test/b", "http://other.test"},
+			want:    2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDb(t)
+			checker := newFakeChecker(true)
+
+			var targets []models.Target
+			for _, uri := range tt.uris {
+				targets = append(targets, newTestTarget(t, db, uri))
+			}
+
+			messages, _ := startScheduler(t, db, tt.options, checker.check)
+			for _, target := range targets {
+				messages <- pubsub.Message{Topic: models.TargetCreatedTopic, Msg: target.Id()}
+			}
+
+			waitFor(t, "checks to start", func() bool {
+				_, running := checker.stats("")
+				return running >= tt.want
+			})
+
+			// Give the scheduler a chance to start more than it should
+			time.Sleep(100 * time.Millisecond)
+			if _, running := checker.stats(""); running != tt.want {
+				t.Errorf("%d checks running, want %d", running, tt.want)
+			}
+
+			close(checker.release)
+			waitFor(t, "every target to be checked", func() bool {
+				calls, _ := checker.stats("")
+				return calls == len(targets)
+			})
+
+			checker.mu.Lock()
+			defer checker.mu.Unlock()
+			if checker.maxRunning != tt.want {
+				t.Errorf("at most %d checks ran at once, want %d", checker.maxRunning, tt.want)
+			}
+		})
+	}
+}
+
+func TestSchedulerPause(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("resumed by hand", func(t *testing.T) {
+		db := newTestDb(t)
+		checker := newFakeChecker(false)
+		target := newTestTarget(t, db, "http://example.test")
+		if err := target.Pause(ctx, db, time.Time{}); err != nil {
+			t.Fatal(err)
+		}
+
+		messages, _ := startScheduler(t, db, Options{}, checker.check)
+
+		request, _ := json.Marshal(models.CheckRequest{Id: "checkreq_1", TargetId: target.Id()})
+		messages <- pubsub.Message{Topic: models.TargetCreatedTopic, Msg: target.Id()}
+		messages <- pubsub.Message{Topic: models.TargetCheckRequestedTopic, Msg: string(request)}
+
+		time.Sleep(100 * time.Millisecond)
+		if calls, _ := checker.stats(target.Id()); calls != 0 {
+			t.Fatalf("paused target was checked %d times", calls)
+		}
+
+		if err := target.Resume(ctx, db); err != nil {
+			t.Fatal(err)
+		}
+
+		messages <- pubsub.Message{Topic: models.TargetResumedTopic, Msg: target.Id()}
+		waitFor(t, "the resumed target to be checked", func() bool {
+			calls, _ := checker.stats(target.Id())
+			return calls == 1
+		})
+	})
+
+	t.Run("resumed automatically", func(t *testing.T) {
+		db := newTestDb(t)
+		checker := newFakeChecker(false)
+		target := newTestTarget(t, db, "http://example.test")
+		if err := target.Pause(ctx, db, time.Now().Add(time.Second)); err != nil {
+			t.Fatal(err)
+		}
+
+		startScheduler(t, db, Options{}, checker.check)
+
+		waitFor(t, "the target to be resumed and checked", func() bool {
+			calls, _ := checker.stats(target.Id())
+			return calls == 1
+		})
+
+		if err := target.Reload(ctx, db); err != nil {
+			t.Fatal(err)
+		} else if !target.Enabled() || !target.PausedUntil().IsZero() {
+			t.Errorf("target wasn't resumed: enabled=%v paused_until=%s", target.Enabled(), target.PausedUntil())
+		}
+	})
+}
+
+func TestSchedulerRetriesResume(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDb(t)
+
+	target := newTestTarget(t, db, "http://example.test")
+	if err := target.Pause(ctx, db, time.Now().Add(-time.Second)); err != nil {
+		t.Fatal(err)
+	}
+
+	s := &scheduler{ctx: ctx, db: db, entries: make(map[string]*targetEntry)}
+	entry := s.add(&target)
+
+	// Fail to store that the target was resumed
+	if _, err := db.ExecContext(ctx, "CREATE TRIGGER fail_resume BEFORE UPDATE OF enabled ON targets BEGIN SELECT RAISE(ABORT, 'read only'); END"); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	s.runDue(ctx)
+
+	if !entry.paused {
+		t.Fatalf("target was resumed without being stored")
+	} else if entry.index < 0 {
+		t.Fatalf("target was dropped from the queue after failing to resume")
+	} else if retry := entry.nextRun.Sub(now); retry <= 0 || retry > resumeRetryInterval+time.Second {
+		t.Errorf("resume retried in %s, want about %s", retry, resumeRetryInterval)
+	}
+
+	if _, err := db.ExecContext(ctx, "DROP TRIGGER fail_resume"); err != nil {
+		t.Fatal(err)
+	}
+
+	s.queue.push(entry, now)
+	s.runDue(ctx)
+
+	if entry.paused || !entry.checkDue {
+		t.Errorf("target wasn't resumed and checked on retrying: paused=%v check_due=%v", entry.paused, entry.checkDue)
+	}
+}
+
+func TestSchedulerShutdown(t *testing.T) {
+	tests := []struct {
+		name    string
+		timeout time.Duration
+		// release lets the check finish once shutdown has started
+		release     bool
+		wantResults int
+	}{
+		{name: "waits for checks in progress", timeout: time.Minute, release: true, wantResults: 1},
+		{name: "cancels checks after the timeout", timeout: 100 * time.Millisecond, wantResults: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDb(t)
+			checker := newFakeChecker(true)
+			target := newTestTarget(t, db, "http://example.test")
+
+			messages, stop := startScheduler(t, db, Options{ShutdownTimeout: tt.timeout}, checker.check)
+			messages <- pubsub.Message{Topic: models.TargetCreatedTopic, Msg: target.Id()}
+			waitFor(t, "the check to start", func() bool {
+				_, running := checker.stats("")
+				return running == 1
+			})
+
+			stopped := make(chan struct{})
+			go func() {
+				stop()
+				close(stopped)
+			}()
+
+			if tt.release {
+				select {
+				case <-stopped:
+					t.Fatal("stopped without waiting for the check in progress")
+				case <-time.After(100 * time.Millisecond):
+				}
+
+				close(checker.release)
+			}
+
+			select {
+			case <-stopped:
+			case <-time.After(5 * time.Second):
+				t.Fatal("timed out waiting for the scheduler to stop")
+			}
+
+			results, err := models.FindCheckResultsByTargetId(context.Background(), db, target.Id(), 10)
+			if err != nil {
+				t.Fatal(err)
+			} else if len(results) != tt.wantResults {
+				t.Errorf("recorded %d results, want %d", len(results), tt.wantResults)
+			}
+		})
+	}
+}
+
+func TestSchedulerHeartbeatPings(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name        string
+		signals     []string
+		wantMoved   bool
+		wantResults int
+	}{
+		{name: "start", signals: []string{models.PingSignalStart}},
+		{name: "success", signals: []string{models.PingSignalSuccess}, wantMoved: true, wantResults: 1},
+		{name: "fail", signals: []string{models.PingSignalFail}, wantResults: 1},
+		{name: "start then success", signals: []string{models.PingSignalStart, models.PingSignalSuccess}, wantMoved: true, wantResults: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db := newTestDb(t)
+			target := models.Target{Kind: models.TargetKindHeartbeat, Period: 60}
+			if err := target.Save(ctx, db); err != nil {
+				t.Fatal(err)
+			}
+
+			s := &scheduler{ctx: ctx, db: db, entries: make(map[string]*targetEntry)}
+			entry := s.add(&target)
+			deadline := entry.nextRun
+
+			time.Sleep(10 * time.Millisecond)
+			for _, signal := range tt.signals {
+				ping, _ := json.Marshal(models.Ping{TargetId: target.Id(), Signal: signal})
+				s.handleMessage(ctx, pubsub.Message{Topic: models.TargetPingedTopic, Msg: string(ping)})
+			}
+
+			if moved := entry.nextRun.After(deadline); moved != tt.wantMoved {
+				t.Errorf("deadline moved from %s to %s, want moved: %v", deadline, entry.nextRun, tt.wantMoved)
+			}
+
+			if len(entry.results) != tt.wantResults {
+				t.Errorf("queued %d results, want %d", len(entry.results), tt.wantResults)
+			}
+		})
+	}
+}
+
+func TestSchedulerHeartbeatStartThenSilence(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDb(t)
+
+	target := models.Target{Kind: models.TargetKindHeartbeat, Period: 2}
+	if err := target.Save(ctx, db); err != nil {
+		t.Fatal(err)
+	}
+
+	messages, _ := startScheduler(t, db, Options{}, newFakeChecker(false).check)
+	deadline := time.Now().Add(2 * time.Second)
+
+	// The job starts halfway to the deadline and then hangs
+	time.Sleep(time.Second)
+	ping, _ := json.Marshal(models.Ping{TargetId: target.Id(), Signal: models.PingSignalStart})
+	messages <- pubsub.Message{Topic: models.TargetPingedTopic, Msg: string(ping)}
+
+	waitFor(t, "the missed ping to be recorded", func() bool {
+		results, err := models.FindCheckResultsByTargetId(ctx, db, target.Id(), 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return len(results) > 0 && results[0].Status == models.StatusDown
+	})
+
+	// A start pushing the deadline back would only miss a period after it
+	if late := time.Since(deadline); late > 700*time.Millisecond {
+		t.Errorf("missed ping recorded %s after the deadline, want the start not to move it", late)
+	}
+}
@@ -4,12 +4,38 @@ import (
 	"context"
 	"database/sql"
 	"log"
+	"time"
 
 	"github.com/tehlordvortex/updawg/config"
 )
 
 var logger = log.New(config.GetLogFile(), "", log.Default().Flags()|log.Lmsgprefix|log.Llongfile)
 
-func Run(ctx context.Context, db *sql.DB) {
-	go runTargetsWorker(ctx, db)
+// Options configure how many checks run at once and how quickly.
+type Options struct {
+	// PoolSize is the number of checks which may run at once.
+	PoolSize int
+	// HostConcurrency limits the checks running against any one host. Zero
+	// is unlimited.
+	HostConcurrency int
+	// Rate and HostRate limit how many checks start each second, overall
+	// and against any one host. Zero is unlimited.
+	Rate     float64
+	HostRate float64
+	// ShutdownTimeout is how long to wait for checks in progress to finish
+	// and record their results once ctx is done, before cancelling them.
+	ShutdownTimeout time.Duration
+	// LeaseTtl is how long leases on targets last without being renewed.
+	// Leases are renewed every third of it.
+	LeaseTtl time.Duration
+}
+
+// Run monitors targets until ctx is done, then returns once the checks in
+// progress have finished or been cancelled.
+func Run(ctx context.Context, db *sql.DB, options Options) {
+	options.PoolSize = max(options.PoolSize, 1)
+	if options.LeaseTtl <= 0 {
+		options.LeaseTtl = config.GetLeaseTtl()
+	}
+	runTargetsWorker(ctx, db, options)
 }